@@ -0,0 +1,144 @@
+package appmetrica
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ReportGroup is a size of time interval of time series report.
+type ReportGroup string
+
+const (
+	RG_Hour  ReportGroup = "hour"
+	RG_Day   ReportGroup = "day"
+	RG_Week  ReportGroup = "week"
+	RG_Month ReportGroup = "month"
+)
+
+// ByTimeQuery describes time series query of Reporting API. Dates of range
+// are taken in their own time zone as in ReportQuery. Reporting API returns
+// time intervals in time zone of application so that Location should be set
+// to it (see SetApplication) in order to parse them; UTC is used if Location
+// is nil.
+type ByTimeQuery struct {
+	ReportQuery
+	Group    ReportGroup
+	Location *time.Location
+}
+
+// SetApplication makes query for single application and sets its time zone.
+func (q *ByTimeQuery) SetApplication(app *Application) error {
+	var loc, err = app.Location()
+
+	if err != nil {
+		return err
+	}
+
+	q.IDs = []int{int(app.ID)}
+	q.Location = loc
+	return nil
+}
+
+// Validate checks parameters of query.
+func (q *ByTimeQuery) Validate() error {
+	switch q.Group {
+	case RG_Hour, RG_Day, RG_Week, RG_Month:
+	case "":
+		return errors.New(prefix + "report group is not specified")
+	default:
+		return errors.New(prefix + "unknown report group: " + string(q.Group))
+	}
+
+	return q.ReportQuery.Validate()
+}
+
+func (q *ByTimeQuery) location() *time.Location {
+	if q.Location != nil {
+		return q.Location
+	}
+	return time.UTC
+}
+
+// ReportByTime выполняет запрос временных рядов к Reporting API
+// (stat/v1/data/bytime). Значения метрик каждой строки выровнены по началам
+// временных интервалов, которые возвращаются в часовом поясе запроса.
+func (c *Client) ReportByTime(ctx context.Context, query *ByTimeQuery) (*ByTimeReport, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	req, res := c.prepare()
+	query.encode(req, `/stat/v1/data/bytime`)
+	req.URI().QueryArgs().Set(`group`, string(query.Group))
+
+	var report ByTimeReport
+
+	if err := c.report(ctx, req, res, &report); err != nil {
+		return nil, err
+	}
+
+	if err := report.align(query.location()); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// align parses beginnings of time intervals in location and checks that all
+// series have value for every interval.
+func (r *ByTimeReport) align(loc *time.Location) error {
+	r.Buckets = make([]time.Time, len(r.TimeIntervals))
+
+	for i, interval := range r.TimeIntervals {
+		if len(interval) == 0 {
+			return errors.New(prefix + "empty time interval in report")
+		}
+
+		var bucket, err = parseReportTime(interval[0], loc)
+
+		if err != nil {
+			return err
+		}
+
+		r.Buckets[i] = bucket
+	}
+
+	var check = func(series [][]float64) error {
+		for _, values := range series {
+			if len(values) != len(r.Buckets) {
+				var msg = "series of " + strconv.Itoa(len(values)) +
+					" values do not match " + strconv.Itoa(len(r.Buckets)) +
+					" time intervals"
+				return errors.New(prefix + msg)
+			}
+		}
+		return nil
+	}
+
+	for _, row := range r.Data {
+		if err := check(row.Metrics); err != nil {
+			return err
+		}
+	}
+
+	return check(r.Totals)
+}
+
+// parseReportTime parses date or datetime of Reporting API in location.
+func parseReportTime(value string, loc *time.Location) (time.Time, error) {
+	var layout = ExportDateFormat
+
+	if len(value) == len(ReportDateFormat) {
+		layout = ReportDateFormat
+	}
+
+	var at, err = time.ParseInLocation(layout, value, loc)
+
+	if err != nil {
+		return at, errors.New(prefix + "invalid time in report: " + value)
+	}
+
+	return at, nil
+}
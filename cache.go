@@ -0,0 +1,179 @@
+package appmetrica
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ReportCache stores raw responses of Reporting API by key of canonicalized
+// query. Implementations should be safe for concurrent use.
+type ReportCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// SetReportCache enables caching of Reporting API responses. Responses of
+// queries which date range could include current day are kept for freshTTL
+// since data of current day is still coming; others are kept for staleTTL.
+// Nil cache disables caching.
+func (c *Client) SetReportCache(cache ReportCache, freshTTL, staleTTL time.Duration) {
+	c.cache = cache
+	c.freshTTL = freshTTL
+	c.staleTTL = staleTTL
+}
+
+// cacheKey returns hash of request path, sorted query arguments and
+// credentials so that the same query made by different users is cached
+// separately.
+func (c *Client) cacheKey(req *fasthttp.Request) string {
+	var args []string
+
+	req.URI().QueryArgs().VisitAll(func(key, value []byte) {
+		args = append(args, string(key)+"="+string(value))
+	})
+
+	sort.Strings(args)
+
+	var hash = sha1.New()
+	hash.Write(req.URI().Path())
+	hash.Write([]byte{'?'})
+	hash.Write([]byte(strings.Join(args, "&")))
+	hash.Write([]byte{0})
+	hash.Write(c.apikey)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// cacheTTL returns lifetime of cached response of request. Range includes
+// today if its end is not before the current date in the westernmost time
+// zone (UTC-12) since time zone of application is unknown here.
+func (c *Client) cacheTTL(req *fasthttp.Request) time.Duration {
+	var args = req.URI().QueryArgs()
+	var today = time.Now().Add(-12 * time.Hour).UTC().Format(ReportDateFormat)
+
+	for _, name := range []string{`date2`, `date2_a`, `date2_b`} {
+		if date := string(args.Peek(name)); date != "" && date >= today {
+			return c.freshTTL
+		}
+	}
+
+	return c.staleTTL
+}
+
+// MemoryReportCache is an in-memory cache which evicts least recently used
+// entries when it is full.
+type MemoryReportCache struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // front is the most recently used entry
+}
+
+type memoryCacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryReportCache creates cache which keeps at most capacity responses.
+func NewMemoryReportCache(capacity int) *MemoryReportCache {
+	return &MemoryReportCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *MemoryReportCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var element, ok = c.entries[key]
+
+	if !ok {
+		return nil, false
+	}
+
+	var entry = element.Value.(*memoryCacheEntry)
+
+	if time.Now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (c *MemoryReportCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var entry = &memoryCacheEntry{key, value, time.Now().Add(ttl)}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.capacity {
+		var oldest = c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// FileReportCache keeps every response in its own file in directory. File
+// starts with expiration time; expired files are removed on access. Files
+// are replaced atomically so that cache could be shared by processes.
+type FileReportCache struct {
+	dir string
+}
+
+// NewFileReportCache creates cache in directory. The directory is created on
+// the first write.
+func NewFileReportCache(dir string) *FileReportCache {
+	return &FileReportCache{dir: dir}
+}
+
+func (c *FileReportCache) path(key string) string {
+	return filepath.Join(c.dir, key+".cache")
+}
+
+func (c *FileReportCache) Get(key string) ([]byte, bool) {
+	var data, err = ioutil.ReadFile(c.path(key))
+
+	if err != nil || len(data) < 8 {
+		return nil, false
+	}
+
+	var expires = time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+
+	if time.Now().After(expires) {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+
+	return data[8:], true
+}
+
+// Set stores response. Errors are ignored since cache is optional.
+func (c *FileReportCache) Set(key string, value []byte, ttl time.Duration) {
+	var header [8]byte
+	binary.BigEndian.PutUint64(header[:], uint64(time.Now().Add(ttl).UnixNano()))
+	writeFileAtomic(c.dir, c.path(key), header[:], value)
+}
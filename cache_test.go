@@ -0,0 +1,127 @@
+package appmetrica
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestMemoryReportCache(t *testing.T) {
+	cache := NewMemoryReportCache(2)
+	cache.Set("a", []byte("1"), time.Hour)
+	cache.Set("b", []byte("2"), time.Hour)
+
+	if value, ok := cache.Get("a"); !ok || string(value) != "1" {
+		t.Fatalf("unexpected value of a: %q (%v)", value, ok)
+	}
+
+	// Entry b is the least recently used one now.
+	cache.Set("c", []byte("3"), time.Hour)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("entry b should be evicted")
+	}
+
+	if _, ok := cache.Get("a"); !ok {
+		t.Errorf("entry a should be kept")
+	}
+
+	cache.Set("a", []byte("4"), -time.Second)
+
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("expired entry a should not be returned")
+	}
+}
+
+func TestFileReportCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appmetrica")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := NewFileReportCache(dir + "/reports")
+	cache.Set("a", []byte(`{"total_rows":1}`), time.Hour)
+	cache.Set("b", []byte(`{}`), -time.Second)
+
+	if value, ok := cache.Get("a"); !ok || string(value) != `{"total_rows":1}` {
+		t.Errorf("unexpected value of a: %q (%v)", value, ok)
+	}
+
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("expired entry b should not be returned")
+	}
+
+	if _, err := os.Stat(cache.path("b")); !os.IsNotExist(err) {
+		t.Errorf("expired entry b should be removed")
+	}
+
+	if _, ok := cache.Get("c"); ok {
+		t.Errorf("missing entry c should not be returned")
+	}
+}
+
+func TestReportCache(t *testing.T) {
+	var requests int
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/x-yametrika+json")
+		json.NewEncoder(w).Encode(&Report{TotalRows: requests})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.SetReportCache(NewMemoryReportCache(16), time.Minute, time.Hour)
+
+	query := &ReportQuery{
+		IDs:     []int{84126},
+		Metrics: Metrics(GE_Users, GE_Sessions),
+		Date1:   time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+		Date2:   time.Date(2018, 1, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	for i := 0; i < 2; i++ {
+		report, err := client.Report(context.Background(), query)
+		if err != nil {
+			t.Fatalf("failed to request report: %s", err)
+		}
+		if report.TotalRows != 1 {
+			t.Errorf("report should be taken from cache: total_rows is %d", report.TotalRows)
+		}
+	}
+
+	query.Limit = 10
+
+	if _, err := client.Report(context.Background(), query); err != nil {
+		t.Fatalf("failed to request report: %s", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("server should get 2 requests instead of %d", requests)
+	}
+
+	req, res := client.prepare()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(res)
+
+	query.encode(req, `/stat/v1/data`)
+
+	if ttl := client.cacheTTL(req); ttl != time.Hour {
+		t.Errorf("past range should be cached for an hour: %s", ttl)
+	}
+
+	query.Date2 = time.Now().AddDate(0, 0, 1)
+	query.encode(req, `/stat/v1/data`)
+
+	if ttl := client.cacheTTL(req); ttl != time.Minute {
+		t.Errorf("range of today should be cached for a minute: %s", ttl)
+	}
+}
@@ -0,0 +1,368 @@
+package appmetrica
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeFields maps export resources to fields which define position of row in
+// time. Incremental export is possible only for resources listed here.
+var timeFields = map[ExportResource]string{
+	ER_Events:          "event_datetime",
+	ER_Installations:   "install_datetime",
+	ER_Clicks:          "click_datetime",
+	ER_Postbacks:       "conversion_datetime",
+	ER_SessionsStarts:  "session_start_datetime",
+	ER_Crashes:         "crash_datetime",
+	ER_Errors:          "error_datetime",
+	ER_PushTokens:      "token_datetime",
+	ER_DeepLinks:       "deeplink_datetime",
+	ER_RevenueEvents:   "event_datetime",
+	ER_EcommerceEvents: "event_datetime",
+	ER_AdRevenueEvents: "event_datetime",
+}
+
+// Checkpoint is a state of incremental export of application resource.
+// HighWater is the end of date range which has been exported completely.
+// Seen contains fingerprints of all rows between SeenSince and HighWater;
+// they are used to deduplicate rows on the next export which starts at
+// SeenSince.
+type Checkpoint struct {
+	ApplicationID int            `json:"application_id"`
+	Resource      ExportResource `json:"resource"`
+	HighWater     time.Time      `json:"high_water"`
+	SeenSince     time.Time      `json:"seen_since,omitempty"`
+	Seen          []string       `json:"seen,omitempty"`
+}
+
+// CheckpointStore persists checkpoints of incremental exports. Load returns
+// nil checkpoint without error if there is no checkpoint yet.
+type CheckpointStore interface {
+	Load(id int, resource ExportResource) (*Checkpoint, error)
+	Save(checkpoint *Checkpoint) error
+}
+
+// FileCheckpointStore keeps every checkpoint in its own JSON file in
+// directory. Files are replaced atomically.
+type FileCheckpointStore struct {
+	dir string
+}
+
+// NewFileCheckpointStore creates checkpoint store in directory. The directory
+// is created on the first save.
+func NewFileCheckpointStore(dir string) *FileCheckpointStore {
+	return &FileCheckpointStore{dir: dir}
+}
+
+func (s *FileCheckpointStore) path(id int, resource ExportResource) string {
+	return filepath.Join(s.dir, strconv.Itoa(id)+"-"+string(resource)+".json")
+}
+
+func (s *FileCheckpointStore) Load(id int, resource ExportResource) (*Checkpoint, error) {
+	var data, err = ioutil.ReadFile(s.path(id, resource))
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var checkpoint = new(Checkpoint)
+
+	if err = json.Unmarshal(data, checkpoint); err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+func (s *FileCheckpointStore) Save(checkpoint *Checkpoint) error {
+	var data, err = json.Marshal(checkpoint)
+
+	if err != nil {
+		return err
+	}
+
+	var path = s.path(checkpoint.ApplicationID, checkpoint.Resource)
+	return writeFileAtomic(s.dir, path, data)
+}
+
+// writeFileAtomic writes concatenation of data to temporary file in dir and
+// renames it to path so that readers never see partially written file.
+// Temporary file is removed on failure.
+func writeFileAtomic(dir, path string, data ...[]byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var file, err = ioutil.TempFile(dir, "."+filepath.Base(path)+"-")
+
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range data {
+		if _, err = file.Write(chunk); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+	}
+
+	return err
+}
+
+// IncrementalExporter exports only rows which have appeared since previous
+// export. Export starts Overlap before high-water mark of the previous one in
+// order to catch data which came late; rows in overlap which have been
+// already exported are skipped. Checkpoint is saved when all rows are read.
+//
+// Fingerprints of rows in overlap are kept in memory and in checkpoint.
+// MaxSeen limits their number: if overlap contains more rows then it is
+// shortened to the latest seconds which fit the limit. Zero MaxSeen means
+// no limit.
+type IncrementalExporter struct {
+	Exporter *ChunkedExporter
+	Store    CheckpointStore
+	Overlap  time.Duration
+	MaxSeen  int
+}
+
+// NewIncrementalExporter creates incremental exporter with one hour overlap
+// of at most 100000 rows.
+func NewIncrementalExporter(exporter *ChunkedExporter, store CheckpointStore) *IncrementalExporter {
+	return &IncrementalExporter{
+		Exporter: exporter,
+		Store:    store,
+		Overlap:  time.Hour,
+		MaxSeen:  100000,
+	}
+}
+
+// Export exports query incrementally. Query.Since is used only if there is no
+// checkpoint yet; zero Query.Until means current time. Time field of
+// resource (e.g. event_datetime for events) is added to field list if it is
+// missing.
+func (e *IncrementalExporter) Export(ctx context.Context, query *ExportQuery) (*ExportReader, error) {
+	var field, ok = timeFields[query.Resource]
+
+	if !ok {
+		var msg = "incremental export is not supported for " + string(query.Resource)
+		return nil, errors.New(prefix + msg)
+	}
+
+	var checkpoint, err = e.Store.Load(query.ApplicationID, query.Resource)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var subquery = *query
+	subquery.Fields = expandFields(query.Resource, query.Fields)
+
+	var loc = subquery.location()
+
+	if subquery.Until.IsZero() {
+		subquery.Until = time.Now().In(loc)
+	}
+
+	// Round to seconds since this is precision of Logs API.
+	subquery.Until = subquery.Until.Truncate(time.Second)
+
+	if checkpoint != nil {
+		// Rows before SeenSince could not be deduplicated.
+		var since = checkpoint.HighWater.Add(-e.Overlap)
+
+		if checkpoint.SeenSince.After(since) {
+			since = checkpoint.SeenSince
+		}
+
+		subquery.Since = since.In(loc)
+	} else {
+		checkpoint = &Checkpoint{
+			ApplicationID: query.ApplicationID,
+			Resource:      query.Resource,
+		}
+	}
+
+	var column = -1
+
+	for i, name := range subquery.Fields {
+		if name == field {
+			column = i
+		}
+	}
+
+	if column == -1 {
+		column = len(subquery.Fields)
+		subquery.Fields = append(subquery.Fields[:column:column], field)
+	}
+
+	var reader *ExportReader
+
+	if reader, err = e.Exporter.Export(ctx, &subquery); err != nil {
+		return nil, err
+	}
+
+	var source = &dedupSource{
+		source:   reader.source,
+		store:    e.Store,
+		previous: checkpoint,
+		column:   column,
+		location: loc,
+		seen:     make(map[string]struct{}, len(checkpoint.Seen)),
+		buckets:  make(map[int64][]string),
+		limit:    e.MaxSeen,
+		upcoming: &Checkpoint{
+			ApplicationID: query.ApplicationID,
+			Resource:      query.Resource,
+			HighWater:     subquery.Until,
+		},
+	}
+
+	for _, fingerprint := range checkpoint.Seen {
+		source.seen[fingerprint] = struct{}{}
+	}
+
+	source.tail = subquery.Until.Add(-e.Overlap)
+	reader = newExportReader(source)
+	reader.SetLocation(loc)
+	return reader, nil
+}
+
+// dedupSource skips rows which have been exported before previous high-water
+// mark and saves the next checkpoint when source is exhausted.
+type dedupSource struct {
+	source   rowSource
+	store    CheckpointStore
+	previous *Checkpoint
+	upcoming *Checkpoint
+	column   int
+	location *time.Location
+	seen     map[string]struct{}
+	tail     time.Time          // fingerprints of rows after tail go to next checkpoint
+	buckets  map[int64][]string // fingerprints of rows after tail by second
+	count    int                // number of fingerprints in buckets
+	limit    int
+	saved    bool
+}
+
+func (s *dedupSource) fields() []string {
+	return s.source.fields()
+}
+
+func (s *dedupSource) next() ([]string, error) {
+	for {
+		var values, err = s.source.next()
+
+		if err == io.EOF && !s.saved {
+			s.saved = true
+			s.upcoming.SeenSince = s.tail
+
+			for _, fingerprints := range s.buckets {
+				s.upcoming.Seen = append(s.upcoming.Seen, fingerprints...)
+			}
+
+			sort.Strings(s.upcoming.Seen)
+
+			if err = s.store.Save(s.upcoming); err == nil {
+				err = io.EOF
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var at time.Time
+
+		if at, err = time.ParseInLocation(ExportDateFormat, values[s.column], s.location); err != nil {
+			return nil, errors.New(prefix + "failed to parse time of row: " + err.Error())
+		}
+
+		var fingerprint = fingerprintRow(values)
+
+		if !at.Before(s.tail) {
+			s.remember(at, fingerprint)
+		}
+
+		if !at.After(s.previous.HighWater) {
+			if _, ok := s.seen[fingerprint]; ok {
+				continue
+			}
+		}
+
+		return values, nil
+	}
+}
+
+// remember adds fingerprint of row to the next checkpoint.
+func (s *dedupSource) remember(at time.Time, fingerprint string) {
+	var second = at.Unix()
+	s.buckets[second] = append(s.buckets[second], fingerprint)
+	s.count++
+
+	if s.limit > 0 && s.count > s.limit {
+		s.trim()
+	}
+}
+
+// trim drops fingerprints of the earliest seconds and moves tail after them
+// so that fingerprints after tail are still complete. Three quarters of
+// limit are left in order to trim rarely.
+func (s *dedupSource) trim() {
+	var seconds = make([]int64, 0, len(s.buckets))
+
+	for second := range s.buckets {
+		seconds = append(seconds, second)
+	}
+
+	sort.Slice(seconds, func(i, j int) bool {
+		return seconds[i] < seconds[j]
+	})
+
+	for _, second := range seconds {
+		if s.count <= s.limit*3/4 {
+			break
+		}
+
+		s.count -= len(s.buckets[second])
+		delete(s.buckets, second)
+		s.tail = time.Unix(second+1, 0).In(s.location)
+	}
+}
+
+func (s *dedupSource) close() error {
+	return s.source.close()
+}
+
+// fingerprintRow returns short hash of row values.
+func fingerprintRow(values []string) string {
+	var hash = sha1.Sum([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(hash[:12])
+}
@@ -0,0 +1,222 @@
+package appmetrica
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appmetrica-checkpoint-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileCheckpointStore(dir)
+
+	t.Run("Store", func(t *testing.T) {
+		if checkpoint, err := store.Load(84126, ER_Events); err != nil || checkpoint != nil {
+			t.Fatalf("unexpected checkpoint in empty store: %v (%v)", checkpoint, err)
+		}
+
+		saved := &Checkpoint{
+			ApplicationID: 84126,
+			Resource:      ER_Events,
+			HighWater:     time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+			Seen:          []string{"abc"},
+		}
+
+		if err := store.Save(saved); err != nil {
+			t.Fatalf("failed to save checkpoint: %s", err)
+		}
+
+		loaded, err := store.Load(84126, ER_Events)
+		if err != nil {
+			t.Fatalf("failed to load checkpoint: %s", err)
+		}
+
+		if !loaded.HighWater.Equal(saved.HighWater) || len(loaded.Seen) != 1 {
+			t.Errorf("wrong checkpoint loaded: %+v", loaded)
+		}
+	})
+
+	t.Run("Dedup", func(t *testing.T) {
+		csv := ioutil.NopCloser(strings.NewReader("" +
+			"event_name,event_datetime\n" +
+			"launch,2018-08-01 23:00:00\n" +
+			"close,2018-08-01 23:30:00\n" +
+			"launch,2018-08-02 01:00:00\n"))
+
+		source, err := newCSVSource(csv)
+		if err != nil {
+			t.Fatalf("failed to read csv: %s", err)
+		}
+
+		previous := &Checkpoint{
+			HighWater: time.Date(2018, 8, 2, 0, 0, 0, 0, time.UTC),
+			Seen:      []string{fingerprintRow([]string{"launch", "2018-08-01 23:00:00"})},
+		}
+
+		dedup := &dedupSource{
+			source:   source,
+			store:    store,
+			previous: previous,
+			column:   1,
+			location: time.UTC,
+			seen:     map[string]struct{}{previous.Seen[0]: {}},
+			buckets:  make(map[int64][]string),
+			tail:     time.Date(2018, 8, 2, 0, 30, 0, 0, time.UTC),
+			upcoming: &Checkpoint{
+				ApplicationID: 84126,
+				Resource:      ER_Installations,
+				HighWater:     time.Date(2018, 8, 2, 1, 30, 0, 0, time.UTC),
+			},
+		}
+
+		reader := newExportReader(dedup)
+		var names []string
+
+		for reader.Next() {
+			names = append(names, reader.Values()[0])
+		}
+
+		if err := reader.Err(); err != nil {
+			t.Fatalf("failed to read rows: %s", err)
+		}
+
+		if strings.Join(names, ",") != "close,launch" {
+			t.Errorf("wrong rows after deduplication: %v", names)
+		}
+
+		checkpoint, err := store.Load(84126, ER_Installations)
+		if err != nil || checkpoint == nil {
+			t.Fatalf("checkpoint was not saved: %v", err)
+		}
+
+		if len(checkpoint.Seen) != 1 {
+			t.Errorf("wrong number of rows in overlap: %d", len(checkpoint.Seen))
+		}
+	})
+}
+
+// memoryCheckpointStore keeps checkpoints in memory.
+type memoryCheckpointStore map[ExportResource]Checkpoint
+
+func (s memoryCheckpointStore) Load(id int, resource ExportResource) (*Checkpoint, error) {
+	if checkpoint, ok := s[resource]; ok {
+		return &checkpoint, nil
+	}
+	return nil, nil
+}
+
+func (s memoryCheckpointStore) Save(checkpoint *Checkpoint) error {
+	s[checkpoint.Resource] = *checkpoint
+	return nil
+}
+
+func TestIncrementalExporter(t *testing.T) {
+	var since, fields string
+	var rows []string
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since = r.URL.Query().Get("date_since")
+		fields = r.URL.Query().Get("fields")
+		fmt.Fprintln(w, "event_name,event_datetime")
+		for _, row := range rows {
+			if row[strings.Index(row, ",")+1:] >= since {
+				fmt.Fprintln(w, row)
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.limiters[logsAPI] = rate.NewLimiter(rate.Inf, 1)
+
+	store := make(memoryCheckpointStore)
+	exporter := NewIncrementalExporter(NewChunkedExporter(client), store)
+	exporter.MaxSeen = 2
+
+	query := &ExportQuery{
+		ApplicationID: 84126,
+		Resource:      ER_Events,
+		Fields:        []string{"event_name"},
+		Since:         time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2018, 8, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	var export = func() string {
+		reader, err := exporter.Export(context.Background(), query)
+		if err != nil {
+			t.Fatalf("failed to start export: %s", err)
+		}
+		defer reader.Close()
+
+		var names []string
+
+		for reader.Next() {
+			if values := reader.Values(); len(values) != 2 {
+				t.Fatalf("time column is not added: %v", values)
+			}
+			names = append(names, reader.Values()[0])
+		}
+
+		if err := reader.Err(); err != nil {
+			t.Fatalf("failed to read export: %s", err)
+		}
+
+		return strings.Join(names, ",")
+	}
+
+	rows = []string{
+		"a,2018-08-01 10:00:00",
+		"b,2018-08-01 11:30:00",
+		"c,2018-08-01 11:45:00",
+		"d,2018-08-01 11:59:00",
+	}
+
+	if names := export(); names != "a,b,c,d" {
+		t.Errorf("wrong rows of the first export: %s", names)
+	}
+
+	if since != "2018-08-01 00:00:00" || fields != "event_name,event_datetime" {
+		t.Errorf("wrong first query: since %q, fields %q", since, fields)
+	}
+
+	// Overlap starts at 11:00 but it contains three rows which exceed the
+	// limit so that it is shortened to the latest row.
+	var checkpoint = store[ER_Events]
+
+	if len(checkpoint.Seen) != 1 || !checkpoint.SeenSince.Equal(time.Date(2018, 8, 1, 11, 45, 1, 0, time.UTC)) {
+		t.Errorf("wrong checkpoint: %+v", checkpoint)
+	}
+
+	query.Until = time.Date(2018, 8, 1, 14, 0, 0, 0, time.UTC)
+	rows = []string{
+		"c,2018-08-01 11:45:00",
+		"e,2018-08-01 11:50:00",
+		"d,2018-08-01 11:59:00",
+		"f,2018-08-01 13:00:00",
+	}
+
+	if names := export(); names != "e,f" {
+		t.Errorf("wrong rows of the second export: %s", names)
+	}
+
+	if since != "2018-08-01 11:45:01" {
+		t.Errorf("export should start with the first deduplicated second: %q", since)
+	}
+
+	if checkpoint = store[ER_Events]; !checkpoint.HighWater.Equal(query.Until) {
+		t.Errorf("wrong high-water mark: %s", checkpoint.HighWater)
+	}
+}
@@ -0,0 +1,359 @@
+package appmetrica
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ExportWindow is a size of time window which export date range is split
+// into by ChunkedExporter.
+type ExportWindow int
+
+const (
+	EW_Day ExportWindow = iota
+	EW_Hour
+)
+
+// next returns the beginning of window which follows the window containing
+// t. Windows are aligned to calendar days or hours in location of t.
+func (w ExportWindow) next(t time.Time) time.Time {
+	var year, month, day = t.Date()
+
+	switch w {
+	case EW_Hour:
+		return time.Date(year, month, day, t.Hour()+1, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// split splits closed date range [since, until] into consecutive closed
+// windows. Bounds of windows have precision of seconds as Logs API does.
+func (w ExportWindow) split(since, until time.Time) [][2]time.Time {
+	var windows [][2]time.Time
+
+	for begin := since; !begin.After(until); {
+		var next = w.next(begin)
+		var end = next.Add(-time.Second)
+
+		if end.After(until) {
+			end = until
+		}
+
+		windows = append(windows, [2]time.Time{begin, end})
+		begin = next
+	}
+
+	return windows
+}
+
+// ChunkedExporter exports large date ranges from Logs API. It splits date
+// range of query into windows, exports at most Concurrency windows at once
+// and merges them into single stream of rows ordered by windows. Export of
+// window is retried up to Retries times on network and server failures.
+// Window which fails in the middle is exported again and rows which have
+// been already read are skipped by their fingerprints since order of rows is
+// not guaranteed between exports. Failures in the middle of window have their
+// own budget of Retries retries. Fingerprints of rows of current window are
+// kept in memory unless Retries is zero.
+type ChunkedExporter struct {
+	Client      *Client
+	Window      ExportWindow
+	Concurrency int
+	Retries     int
+	RetryDelay  time.Duration
+}
+
+// NewChunkedExporter creates exporter with daily windows, four concurrent
+// exports and three retries per window.
+func NewChunkedExporter(client *Client) *ChunkedExporter {
+	return &ChunkedExporter{
+		Client:      client,
+		Window:      EW_Day,
+		Concurrency: 4,
+		Retries:     3,
+		RetryDelay:  5 * time.Second,
+	}
+}
+
+// Export starts export of query and returns reader of merged rows. Rows have
+// the same order of fields regardless of export format. Reader should be
+// closed in order to stop exports of remaining windows.
+func (e *ChunkedExporter) Export(ctx context.Context, query *ExportQuery) (*ExportReader, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Align windows to days of time zone which API uses.
+	var loc = query.location()
+	var windows = e.Window.split(query.Since.In(loc), query.Until.In(loc))
+	var source = &windowSource{
+		exporter: e,
+		query:    query,
+		windows:  windows,
+		results:  make([]chan windowResult, len(windows)),
+		slots:    make(chan struct{}, e.concurrency()),
+		header:   query.fields(),
+	}
+
+	for i := range source.results {
+		source.results[i] = make(chan windowResult, 1)
+	}
+
+	source.ctx, source.cancel = context.WithCancel(ctx)
+	go source.dispatch()
+
+	var reader = newExportReader(source)
+	reader.SetLocation(loc)
+	return reader, nil
+}
+
+func (e *ChunkedExporter) concurrency() int {
+	if e.Concurrency < 1 {
+		return 1
+	}
+	return e.Concurrency
+}
+
+// open exports single window retrying on failures.
+func (e *ChunkedExporter) open(ctx context.Context, query *ExportQuery, window [2]time.Time) (*ExportReader, error) {
+	for attempt := 0; ; attempt++ {
+		var reader, err = e.openOnce(ctx, query, window)
+
+		if err == nil || attempt >= e.Retries || ctx.Err() != nil || !retryable(err) {
+			return reader, err
+		}
+
+		if err = sleep(ctx, e.RetryDelay<<uint(attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// openOnce exports single window without retries.
+func (e *ChunkedExporter) openOnce(ctx context.Context, query *ExportQuery, window [2]time.Time) (*ExportReader, error) {
+	var subquery = *query
+	subquery.Since, subquery.Until = window[0], window[1]
+	return e.Client.ExportRows(ctx, &subquery)
+}
+
+// sleep pauses for duration d or until context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type windowResult struct {
+	reader *ExportReader
+	err    error
+}
+
+// windowSource merges rows of windows in order. Window i is exported in
+// background and its reader is passed through results[i]. Slot of
+// concurrency is acquired before export of window and released when window
+// is read completely.
+type windowSource struct {
+	exporter *ChunkedExporter
+	query    *ExportQuery
+	windows  [][2]time.Time
+	results  []chan windowResult
+	slots    chan struct{}
+	header   []string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	index   int           // index of current window
+	reader  *ExportReader // reader of current window
+	mapping []int         // positions of header fields in rows of window
+	values  []string
+	emitted map[string]int // fingerprints of rows of current window read so far
+	pending map[string]int // fingerprints of rows to skip after retry
+	retries int            // number of retries of current window
+}
+
+// dispatch starts export of windows as soon as there is a free slot.
+func (s *windowSource) dispatch() {
+	for i, window := range s.windows {
+		select {
+		case s.slots <- struct{}{}:
+		case <-s.ctx.Done():
+			for _, result := range s.results[i:] {
+				result <- windowResult{err: s.ctx.Err()}
+			}
+			return
+		}
+
+		go func(result chan windowResult, window [2]time.Time) {
+			var reader, err = s.exporter.open(s.ctx, s.query, window)
+			result <- windowResult{reader, err}
+		}(s.results[i], window)
+	}
+}
+
+func (s *windowSource) fields() []string {
+	return s.header
+}
+
+func (s *windowSource) next() ([]string, error) {
+	for s.index < len(s.windows) {
+		if s.reader == nil {
+			select {
+			case result := <-s.results[s.index]:
+				s.results[s.index] = nil // Mark result as received.
+				if result.err != nil {
+					return nil, result.err
+				}
+				s.reader = result.reader
+				s.mapping = nil
+			case <-s.ctx.Done():
+				return nil, s.ctx.Err()
+			}
+		}
+
+		if s.reader.Next() {
+			var values = s.reorder()
+
+			if s.exporter.Retries <= 0 {
+				return values, nil
+			}
+
+			var fingerprint = fingerprintRow(values)
+
+			if s.pending[fingerprint] > 0 {
+				s.pending[fingerprint]--
+				continue
+			}
+
+			if s.emitted == nil {
+				s.emitted = make(map[string]int)
+			}
+
+			s.emitted[fingerprint]++
+			return values, nil
+		}
+
+		var err = s.reader.Err()
+		s.reader.Close()
+		s.reader = nil
+
+		if err == nil {
+			// Window is over: release its slot and move to the next one.
+			<-s.slots
+			s.index, s.retries = s.index+1, 0
+			s.emitted, s.pending = nil, nil
+			continue
+		}
+
+		if err = s.retry(err); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, io.EOF
+}
+
+// retry reopens current window after failure in the middle of it. Rows of
+// reopened window which have been already read are skipped in next. Every
+// attempt to reopen window counts against retries of window.
+func (s *windowSource) retry(cause error) error {
+	for {
+		if s.retries >= s.exporter.Retries || s.ctx.Err() != nil || !retryable(cause) {
+			return cause
+		}
+
+		if err := sleep(s.ctx, s.exporter.RetryDelay<<uint(s.retries)); err != nil {
+			return err
+		}
+
+		s.retries++
+
+		var reader, err = s.exporter.openOnce(s.ctx, s.query, s.windows[s.index])
+
+		if err != nil {
+			cause = err
+			continue
+		}
+
+		s.reader = reader
+		s.mapping = nil
+		s.pending = make(map[string]int, len(s.emitted))
+
+		for fingerprint, count := range s.emitted {
+			s.pending[fingerprint] = count
+		}
+
+		return nil
+	}
+}
+
+// reorder arranges values of current row in order of header since order of
+// fields could differ between windows in JSON export.
+func (s *windowSource) reorder() []string {
+	if s.mapping == nil {
+		var positions = make(map[string]int)
+
+		for i, field := range s.reader.Fields() {
+			positions[field] = i
+		}
+
+		s.mapping = make([]int, len(s.header))
+
+		for i, field := range s.header {
+			if position, ok := positions[field]; ok {
+				s.mapping[i] = position
+			} else {
+				s.mapping[i] = -1
+			}
+		}
+
+		s.values = make([]string, len(s.header))
+	}
+
+	var row = s.reader.Values()
+
+	for i, position := range s.mapping {
+		if position >= 0 {
+			s.values[i] = row[position]
+		} else {
+			s.values[i] = ""
+		}
+	}
+
+	return s.values
+}
+
+// close stops dispatching of windows and closes readers of windows which
+// have been exported but not read.
+func (s *windowSource) close() error {
+	s.cancel()
+
+	if s.reader != nil {
+		s.reader.Close()
+		s.reader = nil
+	}
+
+	var pending = s.results[s.index:]
+	s.index = len(s.windows)
+
+	go func() {
+		for _, result := range pending {
+			if result == nil {
+				continue
+			}
+			if window := <-result; window.reader != nil {
+				window.reader.Close()
+			}
+		}
+	}()
+
+	return nil
+}
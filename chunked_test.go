@@ -0,0 +1,322 @@
+package appmetrica
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// chunkedHandler serves attempt (starting with zero) of export of window
+// (day of August, 2018 starting with zero).
+type chunkedHandler func(w http.ResponseWriter, window, attempt int)
+
+// chunkedServer is a test server of Logs API which counts exports of windows.
+type chunkedServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	attempts map[int]int
+}
+
+func newChunkedServer(handle chunkedHandler) *chunkedServer {
+	var server = &chunkedServer{attempts: make(map[int]int)}
+
+	server.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var since, _ = time.Parse(ExportDateFormat, r.URL.Query().Get("date_since"))
+		var window = since.Day() - 1
+
+		server.mutex.Lock()
+		var attempt = server.attempts[window]
+		server.attempts[window]++
+		server.mutex.Unlock()
+
+		handle(w, window, attempt)
+	}))
+
+	return server
+}
+
+// requests returns number of exports of every window.
+func (s *chunkedServer) requests() map[int]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var requests = make(map[int]int, len(s.attempts))
+
+	for window, attempts := range s.attempts {
+		requests[window] = attempts
+	}
+
+	return requests
+}
+
+// total returns number of exports of all windows.
+func (s *chunkedServer) total() int {
+	var total int
+
+	for _, attempts := range s.requests() {
+		total += attempts
+	}
+
+	return total
+}
+
+func (s *chunkedServer) exporter(concurrency int) *ChunkedExporter {
+	var client = newTestClient(s.Server)
+	client.limiters[logsAPI] = rate.NewLimiter(rate.Inf, 1)
+
+	var exporter = NewChunkedExporter(client)
+	exporter.Concurrency = concurrency
+	exporter.RetryDelay = time.Millisecond
+	return exporter
+}
+
+// writeWindow writes rows of window in CSV format.
+func writeWindow(w http.ResponseWriter, window, rows int) {
+	fmt.Fprintln(w, "event_name")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(w, "w%d-%d\n", window, i)
+	}
+}
+
+// readWindows reads all rows of export and joins them with spaces.
+func readWindows(reader *ExportReader) (string, error) {
+	var rows []string
+
+	for reader.Next() {
+		rows = append(rows, reader.Values()[0])
+	}
+
+	return strings.Join(rows, " "), reader.Err()
+}
+
+func TestChunkedExporter(t *testing.T) {
+	query := &ExportQuery{
+		ApplicationID: 84126,
+		Resource:      ER_Events,
+		Fields:        []string{"event_name"},
+		Since:         time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2018, 8, 4, 23, 59, 59, 0, time.UTC),
+	}
+
+	t.Run("Merge", func(t *testing.T) {
+		server := newChunkedServer(func(w http.ResponseWriter, window, attempt int) {
+			// The earlier window the later it is ready.
+			time.Sleep(time.Duration(4-window) * 20 * time.Millisecond)
+			writeWindow(w, window, 2)
+		})
+		defer server.Close()
+
+		reader, err := server.exporter(2).Export(context.Background(), query)
+		if err != nil {
+			t.Fatalf("failed to start export: %s", err)
+		}
+		defer reader.Close()
+
+		// Windows are not read yet so that only two of them are exported.
+		time.Sleep(200 * time.Millisecond)
+
+		if total := server.total(); total != 2 {
+			t.Errorf("expected 2 concurrent exports instead of %d", total)
+		}
+
+		rows, err := readWindows(reader)
+		if err != nil {
+			t.Fatalf("failed to read export: %s", err)
+		}
+
+		if rows != "w0-0 w0-1 w1-0 w1-1 w2-0 w2-1 w3-0 w3-1" {
+			t.Errorf("rows are not merged in order: %s", rows)
+		}
+
+		if total := server.total(); total != 4 {
+			t.Errorf("expected 4 exports instead of %d", total)
+		}
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		server := newChunkedServer(func(w http.ResponseWriter, window, attempt int) {
+			if window == 2 && attempt < 2 {
+				// Break connection in the middle of window.
+				writeWindow(w, window, 2)
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			}
+			writeWindow(w, window, 3)
+		})
+		defer server.Close()
+
+		reader, err := server.exporter(2).Export(context.Background(), query)
+		if err != nil {
+			t.Fatalf("failed to start export: %s", err)
+		}
+		defer reader.Close()
+
+		rows, err := readWindows(reader)
+		if err != nil {
+			t.Fatalf("failed to read export: %s", err)
+		}
+
+		var expected = "w0-0 w0-1 w0-2 w1-0 w1-1 w1-2 w2-0 w2-1 w2-2 w3-0 w3-1 w3-2"
+
+		if rows != expected {
+			t.Errorf("rows are duplicated or lost after retry: %s", rows)
+		}
+
+		if requests := server.requests(); requests[2] != 3 {
+			t.Errorf("window should be exported 3 times instead of %d", requests[2])
+		}
+	})
+
+	t.Run("RetryReordered", func(t *testing.T) {
+		server := newChunkedServer(func(w http.ResponseWriter, window, attempt int) {
+			if window != 1 {
+				writeWindow(w, window, 1)
+				return
+			}
+			fmt.Fprintln(w, "event_name")
+			if attempt == 0 {
+				// Break connection in the middle of window.
+				fmt.Fprint(w, "w1-0\nw1-1\n")
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			}
+			// Rows of reopened window come in other order.
+			fmt.Fprint(w, "w1-2\nw1-1\nw1-0\n")
+		})
+		defer server.Close()
+
+		reader, err := server.exporter(1).Export(context.Background(), query)
+		if err != nil {
+			t.Fatalf("failed to start export: %s", err)
+		}
+		defer reader.Close()
+
+		rows, err := readWindows(reader)
+		if err != nil {
+			t.Fatalf("failed to read export: %s", err)
+		}
+
+		if rows != "w0-0 w1-0 w1-1 w1-2 w2-0 w3-0" {
+			t.Errorf("rows are duplicated or lost after retry: %s", rows)
+		}
+	})
+
+	t.Run("RetryLimit", func(t *testing.T) {
+		server := newChunkedServer(func(w http.ResponseWriter, window, attempt int) {
+			if window == 1 {
+				writeWindow(w, window, 1)
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			}
+			writeWindow(w, window, 1)
+		})
+		defer server.Close()
+
+		exporter := server.exporter(1)
+		exporter.Retries = 2
+
+		reader, err := exporter.Export(context.Background(), query)
+		if err != nil {
+			t.Fatalf("failed to start export: %s", err)
+		}
+		defer reader.Close()
+
+		if _, err := readWindows(reader); err == nil {
+			t.Errorf("broken window should fail export")
+		}
+
+		if requests := server.requests(); requests[1] != 3 {
+			t.Errorf("window should be exported 3 times instead of %d", requests[1])
+		}
+	})
+
+	t.Run("Permanent", func(t *testing.T) {
+		server := newChunkedServer(func(w http.ResponseWriter, window, attempt int) {
+			if window == 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"code": 400, "message": "invalid fields"}`))
+				return
+			}
+			writeWindow(w, window, 1)
+		})
+		defer server.Close()
+
+		reader, err := server.exporter(1).Export(context.Background(), query)
+		if err != nil {
+			t.Fatalf("failed to start export: %s", err)
+		}
+		defer reader.Close()
+
+		rows, err := readWindows(reader)
+		if err == nil || !strings.Contains(err.Error(), "invalid fields") {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if rows != "w0-0" {
+			t.Errorf("unexpected rows before failure: %s", rows)
+		}
+
+		if requests := server.requests(); requests[1] != 1 {
+			t.Errorf("client error should not be retried: %d requests", requests[1])
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		var finished = make(chan int, 4)
+
+		server := newChunkedServer(func(w http.ResponseWriter, window, attempt int) {
+			defer func() { finished <- window }()
+
+			// Stream rows until client goes away.
+			fmt.Fprintln(w, "event_name")
+			for i := 0; ; i++ {
+				if _, err := fmt.Fprintf(w, "w%d-%d\n", window, i); err != nil {
+					return
+				}
+				if i%100 == 0 {
+					w.(http.Flusher).Flush()
+				}
+			}
+		})
+		defer server.Close()
+
+		reader, err := server.exporter(3).Export(context.Background(), query)
+		if err != nil {
+			t.Fatalf("failed to start export: %s", err)
+		}
+
+		if !reader.Next() {
+			t.Fatalf("failed to read the first row: %s", reader.Err())
+		}
+
+		// Wait until the second and the third windows are exported.
+		for deadline := time.Now().Add(5 * time.Second); server.total() < 3; {
+			if time.Now().After(deadline) {
+				t.Fatalf("windows are not exported concurrently")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		reader.Close()
+
+		for i := 0; i < 3; i++ {
+			select {
+			case <-finished:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("connections of pending windows are not closed")
+			}
+		}
+
+		if total := server.total(); total != 3 {
+			t.Errorf("windows should not be exported after close: %d exports", total)
+		}
+	})
+}
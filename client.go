@@ -2,8 +2,10 @@ package appmetrica
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -13,12 +15,28 @@ import (
 	"golang.org/x/time/rate"
 )
 
+// Identifiers of API groups which have their own rate limits.
+const (
+	managementAPI = iota
+	logsAPI
+	reportingAPI
+)
+
 // Client binds HTTP API to simple function calls.
 type Client struct {
 	apikey     []byte
 	apikeyPost []byte
 	client     *fasthttp.Client
-	limiters   [3]rate.Limiter
+	limiters   [3]*rate.Limiter
+
+	pollInitial time.Duration
+	pollMax     time.Duration
+
+	cache    ReportCache
+	freshTTL time.Duration
+	staleTTL time.Duration
+
+	sampling SamplingPolicy
 }
 
 func NewClient(token string) *Client {
@@ -32,6 +50,10 @@ func NewClient(token string) *Client {
 		ReadTimeout:         60 * time.Second,
 		MaxResponseBodySize: 0, // Unlimited response body.
 	}
+	c.limiters[managementAPI] = rate.NewLimiter(10, 10)
+	c.limiters[logsAPI] = rate.NewLimiter(1, 3)
+	c.limiters[reportingAPI] = rate.NewLimiter(10, 10)
+	c.SetExportPolling(10*time.Second, 5*time.Minute)
 	return c
 }
 
@@ -96,6 +118,333 @@ func (c *Client) DeleteApplication(id int) error {
 	return err
 }
 
+// ListPartners возвращает список рекламных партнёров, к которым могут быть
+// привязаны трекеры.
+func (c *Client) ListPartners() ([]Partner, error) {
+	req, res := c.prepare()
+	uri := req.URI()
+	uri.SetPath(`/management/v1/tracking/partners`)
+	var obj, err = c.do(req, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Partners, nil
+}
+
+// GetTracker возвращает информацию о трекере приложения.
+func (c *Client) GetTracker(id int, trackingID uint64) (*Tracker, error) {
+	req, res := c.prepare()
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/tracker/` + strconv.FormatUint(trackingID, 10))
+	var obj, err = c.do(req, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Tracker, nil
+}
+
+// ListTrackers возвращает список трекеров приложения.
+func (c *Client) ListTrackers(id int) ([]Tracker, error) {
+	req, res := c.prepare()
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) + `/trackers`)
+	var obj, err = c.do(req, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Trackers, nil
+}
+
+// CreateTracker создаёт трекер приложения с указанными настройками
+// перенаправления, постбеков и окон атрибуции.
+func (c *Client) CreateTracker(id int, tracker *Tracker) (*Tracker, error) {
+	req, res := c.prepare()
+	req.Header.SetMethod("POST")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) + `/trackers`)
+
+	var msg = Response{Tracker: tracker}
+	var obj, err = c.do(req, res, &msg)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Tracker, nil
+}
+
+// ModifyTracker изменяет настройки трекера. Трекер определяется полем
+// TrackingID.
+func (c *Client) ModifyTracker(id int, tracker *Tracker) (*Tracker, error) {
+	req, res := c.prepare()
+	req.Header.SetMethod("PUT")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/tracker/` + strconv.FormatUint(tracker.TrackingID, 10))
+
+	var msg = Response{Tracker: tracker}
+	var obj, err = c.do(req, res, &msg)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Tracker, nil
+}
+
+// DeleteTracker удаляет трекер приложения.
+func (c *Client) DeleteTracker(id int, trackingID uint64) error {
+	req, res := c.prepare()
+	req.Header.SetMethod("DELETE")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/tracker/` + strconv.FormatUint(trackingID, 10))
+
+	var _, err = c.do(req, res, nil)
+	return err
+}
+
+// GetPostback возвращает настройки постбека трекера.
+func (c *Client) GetPostback(id int, trackingID, postbackID uint64) (*Postback, error) {
+	req, res := c.prepare()
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/tracker/` + strconv.FormatUint(trackingID, 10) +
+		`/postback/` + strconv.FormatUint(postbackID, 10))
+	var obj, err = c.do(req, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Postback, nil
+}
+
+// ListPostbacks возвращает список постбеков трекера.
+func (c *Client) ListPostbacks(id int, trackingID uint64) ([]Postback, error) {
+	req, res := c.prepare()
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/tracker/` + strconv.FormatUint(trackingID, 10) + `/postbacks`)
+	var obj, err = c.do(req, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Postbacks, nil
+}
+
+// CreatePostback добавляет постбек к трекеру. Перед отправкой запроса
+// настройки постбека проверяются функцией ValidatePostback.
+func (c *Client) CreatePostback(id int, trackingID uint64, postback *Postback) (*Postback, error) {
+	if err := ValidatePostback(postback); err != nil {
+		return nil, err
+	}
+
+	req, res := c.prepare()
+	req.Header.SetMethod("POST")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/tracker/` + strconv.FormatUint(trackingID, 10) + `/postbacks`)
+
+	var msg = Response{Postback: postback}
+	var obj, err = c.do(req, res, &msg)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Postback, nil
+}
+
+// ModifyPostback изменяет настройки постбека. Постбек определяется полем ID.
+func (c *Client) ModifyPostback(id int, trackingID uint64, postback *Postback) (*Postback, error) {
+	if err := ValidatePostback(postback); err != nil {
+		return nil, err
+	}
+
+	req, res := c.prepare()
+	req.Header.SetMethod("PUT")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/tracker/` + strconv.FormatUint(trackingID, 10) +
+		`/postback/` + strconv.FormatUint(postback.ID, 10))
+
+	var msg = Response{Postback: postback}
+	var obj, err = c.do(req, res, &msg)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Postback, nil
+}
+
+// DeletePostback удаляет постбек трекера.
+func (c *Client) DeletePostback(id int, trackingID, postbackID uint64) error {
+	req, res := c.prepare()
+	req.Header.SetMethod("DELETE")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/tracker/` + strconv.FormatUint(trackingID, 10) +
+		`/postback/` + strconv.FormatUint(postbackID, 10))
+
+	var _, err = c.do(req, res, nil)
+	return err
+}
+
+// GetPushCredentials возвращает состояние загруженных учётных данных сервисов
+// push-уведомлений приложения.
+func (c *Client) GetPushCredentials(id int) (*PushCredentials, error) {
+	req, res := c.prepare()
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) + `/push/credentials`)
+	var obj, err = c.do(req, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.PushCredentials, nil
+}
+
+// UploadAPNsCredentials загружает сертификат или ключ APNs. Содержимое
+// сертификата и ключа передаётся в кодировке base64.
+func (c *Client) UploadAPNsCredentials(id int, creds *APNsCredentials) (*PushCredentials, error) {
+	var msg = Response{PushCredentials: &PushCredentials{APNs: creds}}
+	return c.uploadPushCredentials(id, PP_APNs, &msg)
+}
+
+// UploadFCMCredentials загружает серверный ключ FCM.
+func (c *Client) UploadFCMCredentials(id int, creds *FCMCredentials) (*PushCredentials, error) {
+	var msg = Response{PushCredentials: &PushCredentials{FCM: creds}}
+	return c.uploadPushCredentials(id, PP_FCM, &msg)
+}
+
+// DeletePushCredentials удаляет учётные данные указанного сервиса
+// push-уведомлений.
+func (c *Client) DeletePushCredentials(id int, platform PushPlatform) error {
+	req, res := c.prepare()
+	req.Header.SetMethod("DELETE")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/push/credentials/` + string(platform))
+
+	var _, err = c.do(req, res, nil)
+	return err
+}
+
+// ListTestDevices возвращает список тестовых устройств приложения, данные
+// которых исключаются из статистики.
+func (c *Client) ListTestDevices(id int) ([]TestDevice, error) {
+	req, res := c.prepare()
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) + `/test_devices`)
+	var obj, err = c.do(req, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.TestDevices, nil
+}
+
+// AddTestDevice добавляет тестовое устройство. Идентификатор устройства
+// проверяется функцией ValidateTestDevice.
+func (c *Client) AddTestDevice(id int, device *TestDevice) (*TestDevice, error) {
+	if err := ValidateTestDevice(device); err != nil {
+		return nil, err
+	}
+
+	req, res := c.prepare()
+	req.Header.SetMethod("POST")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) + `/test_devices`)
+
+	var msg = Response{TestDevice: device}
+	var obj, err = c.do(req, res, &msg)
+	if err != nil {
+		return nil, err
+	}
+	return obj.TestDevice, nil
+}
+
+// RemoveTestDevice удаляет тестовое устройство.
+func (c *Client) RemoveTestDevice(id int, deviceID uint64) error {
+	req, res := c.prepare()
+	req.Header.SetMethod("DELETE")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/test_device/` + strconv.FormatUint(deviceID, 10))
+
+	var _, err = c.do(req, res, nil)
+	return err
+}
+
+// ListIPFilters возвращает список диапазонов IP-адресов, трафик с которых
+// исключается из статистики.
+func (c *Client) ListIPFilters(id int) ([]IPFilter, error) {
+	req, res := c.prepare()
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) + `/ip_filters`)
+	var obj, err = c.do(req, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.IPFilters, nil
+}
+
+// AddIPFilter добавляет диапазон IP-адресов в фильтр приложения.
+func (c *Client) AddIPFilter(id int, network *net.IPNet) (*IPFilter, error) {
+	if err := ValidateIPNet(network); err != nil {
+		return nil, err
+	}
+
+	req, res := c.prepare()
+	req.Header.SetMethod("POST")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) + `/ip_filters`)
+
+	var msg = Response{IPFilter: &IPFilter{CIDR: network.String()}}
+	var obj, err = c.do(req, res, &msg)
+	if err != nil {
+		return nil, err
+	}
+	return obj.IPFilter, nil
+}
+
+// RemoveIPFilter удаляет диапазон IP-адресов из фильтра приложения.
+func (c *Client) RemoveIPFilter(id int, filterID uint64) error {
+	req, res := c.prepare()
+	req.Header.SetMethod("DELETE")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/ip_filter/` + strconv.FormatUint(filterID, 10))
+
+	var _, err = c.do(req, res, nil)
+	return err
+}
+
+// ListEventNames возвращает список имён событий, полученных приложением,
+// с датами первого и последнего появления.
+func (c *Client) ListEventNames(id int) ([]EventName, error) {
+	req, res := c.prepare()
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) + `/event_names`)
+	var obj, err = c.do(req, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.EventNames, nil
+}
+
+// HideEventNames скрывает события с указанными именами из отчётов.
+func (c *Client) HideEventNames(id int, names ...string) error {
+	return c.setEventNamesVisibility(id, true, names)
+}
+
+// RestoreEventNames возвращает в отчёты ранее скрытые события.
+func (c *Client) RestoreEventNames(id int, names ...string) error {
+	return c.setEventNamesVisibility(id, false, names)
+}
+
 // ImportEvent загружает информацию о событии.
 func (c *Client) ImportEvent(event ImportEvent) error {
 	return ErrNotImplemented
@@ -131,6 +480,50 @@ func (c *Client) SetPostAPIKey(token string) {
 	c.apikeyPost = []byte(token)
 }
 
+// SetExportPolling sets initial and maximal delays between polls of Logs API
+// while export data is being prepared. The delay is doubled after every poll.
+func (c *Client) SetExportPolling(initial, max time.Duration) {
+	c.pollInitial = initial
+	c.pollMax = max
+}
+
+// wait blocks until rate limiter of API group allows a request or context is
+// done.
+func (c *Client) wait(ctx context.Context, api int) error {
+	return c.limiters[api].Wait(ctx)
+}
+
+func (c *Client) setEventNamesVisibility(id int, hidden bool, names []string) error {
+	req, res := c.prepare()
+	req.Header.SetMethod("PUT")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) + `/event_names`)
+
+	var msg = Response{EventNames: make([]EventName, len(names))}
+	for i, name := range names {
+		msg.EventNames[i] = EventName{Name: name, Hidden: hidden}
+	}
+
+	var _, err = c.do(req, res, &msg)
+	return err
+}
+
+func (c *Client) uploadPushCredentials(id int, platform PushPlatform, msg *Response) (*PushCredentials, error) {
+	req, res := c.prepare()
+	req.Header.SetMethod("PUT")
+
+	uri := req.URI()
+	uri.SetPath(`/management/v1/application/` + strconv.Itoa(id) +
+		`/push/credentials/` + string(platform))
+
+	var obj, err = c.do(req, res, msg)
+	if err != nil {
+		return nil, err
+	}
+	return obj.PushCredentials, nil
+}
+
 func (c *Client) do(req *fasthttp.Request, res *fasthttp.Response, msg interface{}) (*Response, error) {
 	var err error
 	var obj Response
@@ -148,19 +541,11 @@ func (c *Client) do(req *fasthttp.Request, res *fasthttp.Response, msg interface
 		}
 	}
 
-	// Print prepared request.
-	println("\033[1mRequest dump string\033[0m")
-	println(req.String())
-
 	// Make request.
 	if err = c.client.Do(req, res); err != nil {
 		return &obj, err
 	}
 
-	// Print received response.
-	println("\033[1mResponse dump string\033[0m")
-	println(res.String())
-
 	contentType := string(res.Header.Peek(`Content-Type`))
 	contentType = strings.Split(contentType, ";")[0]
 
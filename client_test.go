@@ -0,0 +1,126 @@
+package appmetrica
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestClient creates client which sends all requests to TLS server.
+func newTestClient(server *httptest.Server) *Client {
+	client := NewClient("token")
+	client.client.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	client.client.Dial = func(addr string) (net.Conn, error) {
+		return net.Dial("tcp", strings.TrimPrefix(server.URL, "https://"))
+	}
+	return client
+}
+
+// managementRequest is a request received by test server of Management API.
+type managementRequest struct {
+	Method string
+	Path   string
+	Body   Response
+}
+
+// newManagementServer creates server which records requests and replies with
+// reply.
+func newManagementServer(requests *[]managementRequest, reply string) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request = managementRequest{Method: r.Method, Path: r.URL.Path}
+		var body, _ = ioutil.ReadAll(r.Body)
+
+		if len(body) > 0 {
+			json.Unmarshal(body, &request.Body)
+		}
+
+		*requests = append(*requests, request)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(reply))
+	}))
+}
+
+func TestTrackers(t *testing.T) {
+	var requests []managementRequest
+
+	server := newManagementServer(&requests, `{
+		"partners": [{"id": 1, "name": "Partner"}],
+		"tracker": {"tracking_id": 42, "app_id": 84126, "partner_id": 1},
+		"trackers": [{"tracking_id": 42}, {"tracking_id": 43}]
+	}`)
+	defer server.Close()
+
+	client := newTestClient(server)
+
+	partners, err := client.ListPartners()
+	if err != nil {
+		t.Fatalf("failed to list partners: %s", err)
+	}
+	if len(partners) != 1 || partners[0].Name != "Partner" {
+		t.Errorf("unexpected partners: %+v", partners)
+	}
+
+	tracker, err := client.CreateTracker(84126, &Tracker{PartnerID: 1, Name: "tracker"})
+	if err != nil {
+		t.Fatalf("failed to create tracker: %s", err)
+	}
+	if tracker.TrackingID != 42 {
+		t.Errorf("unexpected tracker: %+v", tracker)
+	}
+
+	tracker.AttributionWindow = &AttributionWindow{Reattribution: Bool(false)}
+
+	if _, err = client.ModifyTracker(84126, tracker); err != nil {
+		t.Fatalf("failed to modify tracker: %s", err)
+	}
+
+	if _, err = client.GetTracker(84126, 42); err != nil {
+		t.Fatalf("failed to get tracker: %s", err)
+	}
+
+	trackers, err := client.ListTrackers(84126)
+	if err != nil {
+		t.Fatalf("failed to list trackers: %s", err)
+	}
+	if len(trackers) != 2 {
+		t.Errorf("unexpected trackers: %+v", trackers)
+	}
+
+	if err = client.DeleteTracker(84126, 42); err != nil {
+		t.Fatalf("failed to delete tracker: %s", err)
+	}
+
+	var expected = []managementRequest{
+		{Method: "GET", Path: "/management/v1/tracking/partners"},
+		{Method: "POST", Path: "/management/v1/application/84126/trackers"},
+		{Method: "PUT", Path: "/management/v1/application/84126/tracker/42"},
+		{Method: "GET", Path: "/management/v1/application/84126/tracker/42"},
+		{Method: "GET", Path: "/management/v1/application/84126/trackers"},
+		{Method: "DELETE", Path: "/management/v1/application/84126/tracker/42"},
+	}
+
+	if len(requests) != len(expected) {
+		t.Fatalf("expected %d requests instead of %d", len(expected), len(requests))
+	}
+
+	for i, request := range requests {
+		if request.Method != expected[i].Method || request.Path != expected[i].Path {
+			t.Errorf("unexpected request #%d: %s %s", i, request.Method, request.Path)
+		}
+	}
+
+	if body := requests[1].Body.Tracker; body == nil || body.Name != "tracker" {
+		t.Errorf("tracker is not sent on creation: %+v", body)
+	}
+
+	var window = requests[2].Body.Tracker.AttributionWindow
+
+	if window == nil || window.Reattribution == nil || *window.Reattribution {
+		t.Errorf("disabled reattribution is not sent: %+v", window)
+	}
+}
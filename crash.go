@@ -0,0 +1,459 @@
+package appmetrica
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StackKind is a kind of platform which stack trace comes from.
+type StackKind string
+
+const (
+	SK_Unknown StackKind = "unknown"
+	SK_Java    StackKind = "java"   // Java and Kotlin
+	SK_Apple   StackKind = "apple"  // Objective-C and Swift
+	SK_Native  StackKind = "native" // Android native (NDK) tombstone
+)
+
+// StackFrame is a single frame of stack trace. Module is a binary or library
+// of native and Apple frames; it is empty for Java frames. Line is zero if
+// source position is unknown.
+type StackFrame struct {
+	Module   string
+	Function string
+	File     string
+	Line     int
+	Address  uint64
+}
+
+// StackTrace is a parsed stack trace of crash or error. Exception is a
+// header of Java trace (exception class and message). Cause is a trace of
+// exception which caused this one (`Caused by:` section of Java trace).
+type StackTrace struct {
+	Kind      StackKind
+	Exception string
+	Frames    []StackFrame
+	Cause     *StackTrace
+}
+
+var (
+	// at com.example.Foo.bar(Foo.kt:42)
+	javaFrame = regexp.MustCompile(`^\s*at\s+([^\s(]+)\((.*)\)\s*$`)
+	// 2   MyApp   0x0000000102a3b4c8 -[ViewController tap:] + 12 (ViewController.m:42)
+	appleFrame = regexp.MustCompile(`^\s*\d+\s+(\S+)\s+0x([0-9a-fA-F]+)\s+(.*)$`)
+	// #01 pc 0000000000012345  /data/app/lib/arm64/libnative.so (crash+24)
+	nativeFrame = regexp.MustCompile(`^\s*#\d+\s+pc\s+([0-9a-fA-F]+)\s+(\S+)(?:\s+\((.*?)\))?`)
+	// Apple thread and exception backtrace headers.
+	threadHeader = regexp.MustCompile(`^(Thread \d+( Crashed)?|Last Exception Backtrace)\b`)
+	// signal 11 (SIGSEGV), code 1 (SEGV_MAPERR), fault addr 0x0
+	nativeSignal = regexp.MustCompile(`^signal\s+\d+\s+\(\w+\)(?:,\s+code\s+-?\d+\s+\(\w+\))?`)
+	// Trailing source position of Apple symbol: (File.swift:17).
+	sourcePosition = regexp.MustCompile(`\s*\(([^():]+):(\d+)\)\s*$`)
+)
+
+// ParseStackTrace parses stack trace text of crash or error. Java/Kotlin
+// traces, Apple crash reports (Objective-C/Swift) and Android native
+// tombstones are recognized. If report contains several threads then frames
+// of exception backtrace or of crashed thread are returned. Exception of
+// Apple and native traces is taken from `Exception Type:` and `signal` lines
+// respectively. Unrecognized lines are ignored.
+func ParseStackTrace(text string) *StackTrace {
+	var trace = &StackTrace{Kind: SK_Unknown}
+	var current = trace
+	var exception string
+
+	// Frames of Apple report are grouped by threads; pick the best group.
+	var priority = -1
+	var frames []StackFrame
+	var framesPriority = 0
+
+	var commit = func() {
+		if len(frames) > 0 && framesPriority > priority {
+			priority = framesPriority
+			trace.Frames = frames
+		}
+		frames = nil
+	}
+
+	var scanner = bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(nil, 1<<20)
+
+	for scanner.Scan() {
+		var line = strings.TrimRight(scanner.Text(), "\r")
+		var trimmed = strings.TrimSpace(line)
+
+		if trimmed == "" {
+			continue
+		}
+
+		if match := javaFrame.FindStringSubmatch(line); match != nil {
+			trace.Kind = SK_Java
+			current.Frames = append(current.Frames, parseJavaFrame(match[1], match[2]))
+			continue
+		}
+
+		if match := nativeFrame.FindStringSubmatch(line); match != nil {
+			trace.Kind = SK_Native
+			var address, _ = strconv.ParseUint(match[1], 16, 64)
+			var frame = StackFrame{Module: match[2], Address: address}
+			frame.Function = stripOffset(match[3])
+			frames = append(frames, frame)
+			continue
+		}
+
+		if match := appleFrame.FindStringSubmatch(line); match != nil {
+			trace.Kind = SK_Apple
+			frames = append(frames, parseAppleFrame(match[1], match[2], match[3]))
+			continue
+		}
+
+		if match := threadHeader.FindStringSubmatch(trimmed); match != nil {
+			commit()
+			switch {
+			case strings.HasPrefix(match[1], "Last Exception Backtrace"):
+				framesPriority = 2
+			case match[2] != "":
+				framesPriority = 1
+			default:
+				framesPriority = 0
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "Caused by:"):
+			current.Cause = &StackTrace{
+				Kind:      SK_Java,
+				Exception: strings.TrimSpace(strings.TrimPrefix(trimmed, "Caused by:")),
+			}
+			current = current.Cause
+		case strings.HasPrefix(trimmed, "..."):
+			// Frames in common with enclosing trace are omitted.
+		case strings.HasPrefix(trimmed, "Exception Type:"):
+			exception = strings.TrimSpace(strings.TrimPrefix(trimmed, "Exception Type:"))
+		case strings.HasPrefix(trimmed, "signal ") && exception == "":
+			exception = trimmed
+		case trace.Exception == "" && len(trace.Frames) == 0:
+			trace.Exception = trimmed
+		}
+	}
+
+	commit()
+
+	if trace.Kind != SK_Java {
+		trace.Exception = exception
+	}
+
+	return trace
+}
+
+// parseJavaFrame parses method and source position of Java frame like
+// `com.example.Foo.bar` and `Foo.kt:42`.
+func parseJavaFrame(method, position string) StackFrame {
+	var frame = StackFrame{Function: method}
+
+	if colon := strings.LastIndexByte(position, ':'); colon != -1 {
+		frame.File = position[:colon]
+		frame.Line, _ = strconv.Atoi(position[colon+1:])
+	} else if position != "Native Method" && position != "Unknown Source" {
+		frame.File = position
+	}
+
+	return frame
+}
+
+// parseAppleFrame parses frame of Apple crash report. Symbol could be
+// followed by offset and source position.
+func parseAppleFrame(module, address, symbol string) StackFrame {
+	var frame = StackFrame{Module: module}
+	frame.Address, _ = strconv.ParseUint(address, 16, 64)
+
+	if match := sourcePosition.FindStringSubmatch(symbol); match != nil {
+		frame.File = match[1]
+		frame.Line, _ = strconv.Atoi(match[2])
+		symbol = symbol[:len(symbol)-len(match[0])]
+	}
+
+	frame.Function = stripOffset(symbol)
+
+	// Unsymbolicated frames look like `0x102a3b000 + 1234`.
+	if strings.HasPrefix(frame.Function, "0x") {
+		frame.Function = ""
+	}
+
+	return frame
+}
+
+// stripOffset removes offset from symbol like `abort+120` or `main + 12`.
+func stripOffset(symbol string) string {
+	if plus := strings.LastIndexByte(symbol, '+'); plus != -1 {
+		if _, err := strconv.Atoi(strings.TrimSpace(symbol[plus+1:])); err == nil {
+			symbol = symbol[:plus]
+		}
+	}
+	return strings.TrimSpace(symbol)
+}
+
+// systemPrefixes are prefixes of Java classes, Apple modules and native
+// libraries which belong to platform rather than application.
+var systemPrefixes = map[StackKind][]string{
+	SK_Java: {
+		"java.", "javax.", "kotlin.", "kotlinx.", "android.", "androidx.",
+		"com.android.", "dalvik.", "sun.", "libcore.",
+	},
+	SK_Apple: {
+		"CoreFoundation", "Foundation", "UIKit", "UIKitCore", "GraphicsServices",
+		"libobjc", "libsystem_", "libdyld", "libswift", "libdispatch",
+		"dyld", "CFNetwork", "QuartzCore",
+	},
+	SK_Native: {"/system/", "/apex/", "/vendor/"},
+}
+
+// isSystemFrame reports whether frame belongs to platform code.
+func isSystemFrame(kind StackKind, frame *StackFrame) bool {
+	var name = frame.Module
+
+	if kind == SK_Java {
+		name = frame.Function
+	}
+
+	for _, system := range systemPrefixes[kind] {
+		if strings.HasPrefix(name, system) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Root returns the innermost cause of trace.
+func (t *StackTrace) Root() *StackTrace {
+	var root = t
+	for root.Cause != nil && len(root.Cause.Frames) > 0 {
+		root = root.Cause
+	}
+	return root
+}
+
+// ExceptionType returns class of exception without message, e.g.
+// `java.lang.IllegalStateException`. Signal of native trace is returned with
+// its code but without fault address, e.g.
+// `signal 11 (SIGSEGV), code 1 (SEGV_MAPERR)`.
+func (t *StackTrace) ExceptionType() string {
+	var exception = t.Exception
+
+	if signal := nativeSignal.FindString(exception); signal != "" {
+		return signal
+	}
+
+	if colon := strings.IndexByte(exception, ':'); colon != -1 {
+		exception = exception[:colon]
+	}
+
+	return strings.TrimSpace(exception)
+}
+
+// TopFrames returns at most depth frames of application code from the top of
+// the innermost cause. Frames of platform code are skipped unless there are
+// no others.
+func (t *StackTrace) TopFrames(depth int) []StackFrame {
+	var root = t.Root()
+	var frames []StackFrame
+
+	for i := range root.Frames {
+		if len(frames) == depth {
+			break
+		}
+		if !isSystemFrame(t.Kind, &root.Frames[i]) {
+			frames = append(frames, root.Frames[i])
+		}
+	}
+
+	if len(frames) == 0 {
+		frames = root.Frames
+		if len(frames) > depth {
+			frames = frames[:depth]
+		}
+	}
+
+	return frames
+}
+
+// SignatureDepth is a number of frames which signature of stack trace is
+// computed from.
+const SignatureDepth = 5
+
+// Signature returns hash which is stable across builds and devices: it
+// depends on exception type and on functions (or modules of unsymbolicated
+// frames) of the top frames of application code but not on messages,
+// addresses, offsets and line numbers.
+func (t *StackTrace) Signature() string {
+	var parts = []string{string(t.Kind), t.Root().ExceptionType()}
+
+	for _, frame := range t.TopFrames(SignatureDepth) {
+		var name = frame.Function
+
+		if name == "" {
+			name = frame.Module
+		}
+
+		parts = append(parts, name)
+	}
+
+	var hash = sha1.Sum([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(hash[:8])
+}
+
+// StackTrace parses stack trace of crash.
+func (c *ExportCrash) StackTrace() *StackTrace {
+	return ParseStackTrace(c.Crash)
+}
+
+// StackTrace parses stack trace of error.
+func (e *ExportError) StackTrace() *StackTrace {
+	return ParseStackTrace(e.Error)
+}
+
+// CrashGroup is a group of crashes or errors with the same signature.
+// Versions counts occurrences by app_version_name.
+type CrashGroup struct {
+	Signature string
+	Name      string
+	Kind      StackKind
+	Frames    []StackFrame
+	Count     int
+	Versions  map[string]int
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// CrashGrouper groups crashes and errors by signature of their stack traces.
+type CrashGrouper struct {
+	groups map[string]*CrashGroup
+}
+
+func NewCrashGrouper() *CrashGrouper {
+	return &CrashGrouper{groups: make(map[string]*CrashGroup)}
+}
+
+// AddCrash adds crash to its group. Name of group is taken from exception
+// header of trace or from crash_name if trace has no header. Crashes which
+// trace has no frames (e.g. crash field is not exported) are grouped by
+// crash_group_id or by name if there is no group identifier.
+func (g *CrashGrouper) AddCrash(crash *ExportCrash) {
+	var name = crash.CrashName
+
+	if name == "" {
+		name = crash.CrashReason
+	}
+
+	var key = "crash:" + name
+
+	if crash.CrashGroupID != 0 {
+		key = "crash_group:" + strconv.FormatUint(crash.CrashGroupID, 10)
+	}
+
+	g.add(crash.StackTrace(), name, key, crash.AppVersionName, crash.CrashDatetime)
+}
+
+// AddError adds error to its group. Errors which trace has no frames are
+// grouped by error_name.
+func (g *CrashGrouper) AddError(err *ExportError) {
+	var key = "error:" + err.ErrorName
+	g.add(err.StackTrace(), err.ErrorName, key, err.AppVersionName, err.ErrorDatetime)
+}
+
+// add adds occurrence to group of trace signature or to group of key if
+// trace has no frames to compute signature of.
+func (g *CrashGrouper) add(trace *StackTrace, name, key, version string, at time.Time) {
+	var signature = trace.Signature()
+
+	if len(trace.TopFrames(SignatureDepth)) == 0 {
+		var hash = sha1.Sum([]byte(string(trace.Kind) + "\n" + key))
+		signature = hex.EncodeToString(hash[:8])
+	}
+
+	var group, ok = g.groups[signature]
+
+	if !ok {
+		if exception := trace.Root().Exception; exception != "" {
+			name = exception
+		}
+
+		group = &CrashGroup{
+			Signature: signature,
+			Name:      name,
+			Kind:      trace.Kind,
+			Frames:    trace.TopFrames(SignatureDepth),
+			Versions:  make(map[string]int),
+			FirstSeen: at,
+			LastSeen:  at,
+		}
+
+		g.groups[signature] = group
+	}
+
+	group.Count++
+	group.Versions[version]++
+
+	if !at.IsZero() && (group.FirstSeen.IsZero() || at.Before(group.FirstSeen)) {
+		group.FirstSeen = at
+	}
+
+	if at.After(group.LastSeen) {
+		group.LastSeen = at
+	}
+}
+
+// Groups returns groups ordered by number of occurrences in descending order.
+func (g *CrashGrouper) Groups() []*CrashGroup {
+	var groups = make([]*CrashGroup, 0, len(g.groups))
+
+	for _, group := range g.groups {
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Signature < groups[j].Signature
+	})
+
+	return groups
+}
+
+// GroupCrashes reads crashes or errors export and groups rows by signature
+// of stack trace. Reader is not closed.
+func GroupCrashes(reader *ExportReader, resource ExportResource) ([]*CrashGroup, error) {
+	var grouper = NewCrashGrouper()
+
+	for reader.Next() {
+		switch resource {
+		case ER_Errors:
+			var row ExportError
+			if err := reader.Scan(&row); err != nil {
+				return nil, err
+			}
+			grouper.AddError(&row)
+		default:
+			var row ExportCrash
+			if err := reader.Scan(&row); err != nil {
+				return nil, err
+			}
+			grouper.AddCrash(&row)
+		}
+	}
+
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+
+	return grouper.Groups(), nil
+}
@@ -0,0 +1,181 @@
+package appmetrica
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const javaCrash = `java.lang.RuntimeException: Unable to start activity
+	at android.app.ActivityThread.performLaunchActivity(ActivityThread.java:2946)
+	at android.os.Handler.dispatchMessage(Handler.java:106)
+Caused by: java.lang.IllegalStateException: user 42 is missing
+	at com.example.app.ProfileRepository.load(ProfileRepository.kt:%d)
+	at com.example.app.MainActivity.onCreate(MainActivity.kt:17)
+	at android.app.Activity.performCreate(Native Method)
+	... 2 more
+`
+
+const appleCrash = `Incident Identifier: 6F1A5D2E
+Exception Type:  EXC_CRASH (SIGABRT)
+
+Last Exception Backtrace:
+0   CoreFoundation                	0x00000001a1b2c3d4 __exceptionPreprocess + 228
+1   libobjc.A.dylib               	0x0000000180f8c1ac objc_exception_throw + 56
+2   MyApp                         	0x0000000102a3b4c8 -[ViewController buttonTapped:] + 120 (ViewController.m:42)
+3   MyApp                         	0x0000000102a3b000 0x102a00000 + 241664
+
+Thread 0 Crashed:
+0   libsystem_kernel.dylib        	0x00000001810d4ec4 __pthread_kill + 8
+`
+
+const nativeCrash = `signal 11 (SIGSEGV), code 1 (SEGV_MAPERR), fault addr 0x0
+backtrace:
+    #00 pc 000000000001e4c8  /system/lib64/libc.so (abort+120)
+    #01 pc 0000000000012345  /data/app/com.example/lib/arm64/libnative.so (Java_com_example_Native_crash+24)
+`
+
+func TestStackTrace(t *testing.T) {
+	t.Run("Java", func(t *testing.T) {
+		trace := ParseStackTrace(fmt.Sprintf(javaCrash, 10))
+
+		if trace.Kind != SK_Java || trace.Exception != "java.lang.RuntimeException: Unable to start activity" {
+			t.Fatalf("wrong header of trace: %s %q", trace.Kind, trace.Exception)
+		}
+
+		root := trace.Root()
+		if root.ExceptionType() != "java.lang.IllegalStateException" || len(root.Frames) != 3 {
+			t.Fatalf("wrong cause of trace: %+v", root)
+		}
+
+		frame := root.Frames[0]
+		if frame.Function != "com.example.app.ProfileRepository.load" || frame.File != "ProfileRepository.kt" || frame.Line != 10 {
+			t.Errorf("wrong frame: %+v", frame)
+		}
+
+		if frames := trace.TopFrames(5); len(frames) != 2 {
+			t.Errorf("system frames were not skipped: %+v", frames)
+		}
+
+		// Line numbers should not affect signature.
+		if trace.Signature() != ParseStackTrace(fmt.Sprintf(javaCrash, 11)).Signature() {
+			t.Errorf("signature depends on line numbers")
+		}
+	})
+
+	t.Run("Apple", func(t *testing.T) {
+		trace := ParseStackTrace(appleCrash)
+
+		if trace.Kind != SK_Apple || trace.Exception != "EXC_CRASH (SIGABRT)" {
+			t.Fatalf("wrong header of trace: %s %q", trace.Kind, trace.Exception)
+		}
+
+		if len(trace.Frames) != 4 {
+			t.Fatalf("frames of exception backtrace were not chosen: %+v", trace.Frames)
+		}
+
+		frame := trace.Frames[2]
+		if frame.Module != "MyApp" || frame.Function != "-[ViewController buttonTapped:]" || frame.Line != 42 {
+			t.Errorf("wrong frame: %+v", frame)
+		}
+
+		if frame := trace.Frames[3]; frame.Function != "" || frame.Address != 0x102a3b000 {
+			t.Errorf("wrong unsymbolicated frame: %+v", frame)
+		}
+	})
+
+	t.Run("Native", func(t *testing.T) {
+		trace := ParseStackTrace(nativeCrash)
+
+		if trace.Kind != SK_Native || len(trace.Frames) != 2 {
+			t.Fatalf("wrong trace: %+v", trace)
+		}
+
+		if frame := trace.Frames[1]; frame.Function != "Java_com_example_Native_crash" {
+			t.Errorf("wrong frame: %+v", frame)
+		}
+
+		if frames := trace.TopFrames(5); len(frames) != 1 || frames[0].Address != 0x12345 {
+			t.Errorf("wrong top frames: %+v", frames)
+		}
+
+		if exception := trace.ExceptionType(); exception != "signal 11 (SIGSEGV), code 1 (SEGV_MAPERR)" {
+			t.Errorf("wrong exception type: %q", exception)
+		}
+
+		// Fault address should not affect signature.
+		other := ParseStackTrace(strings.Replace(nativeCrash, "fault addr 0x0", "fault addr 0x7b3c2a10", 1))
+		if trace.Signature() != other.Signature() {
+			t.Errorf("signature depends on fault address")
+		}
+	})
+}
+
+func TestCrashGrouper(t *testing.T) {
+	grouper := NewCrashGrouper()
+	crashes := []ExportCrash{
+		{Crash: fmt.Sprintf(javaCrash, 10)},
+		{Crash: fmt.Sprintf(javaCrash, 12)},
+		{Crash: fmt.Sprintf(javaCrash, 12)},
+		{Crash: nativeCrash},
+	}
+
+	crashes[0].AppVersionName = "1.0"
+	crashes[1].AppVersionName = "1.1"
+	crashes[2].AppVersionName = "1.1"
+	crashes[3].AppVersionName = "1.1"
+
+	for i := range crashes {
+		grouper.AddCrash(&crashes[i])
+	}
+
+	groups := grouper.Groups()
+	if len(groups) != 2 {
+		t.Fatalf("wrong number of groups: %d", len(groups))
+	}
+
+	if group := groups[0]; group.Count != 3 || group.Versions["1.0"] != 1 || group.Versions["1.1"] != 2 {
+		t.Errorf("wrong counts of group: %+v", group)
+	}
+
+	if name := groups[0].Name; name != "java.lang.IllegalStateException: user 42 is missing" {
+		t.Errorf("wrong name of group: %q", name)
+	}
+}
+
+func TestCrashGrouperFallback(t *testing.T) {
+	grouper := NewCrashGrouper()
+
+	// Stack traces are not exported so that crashes are grouped by name or
+	// by identifier of group.
+	for _, crash := range []ExportCrash{
+		{CrashName: "A"},
+		{CrashName: "B"},
+		{CrashName: "C"},
+		{CrashName: "C"},
+		{CrashName: "D", CrashGroupID: 7},
+		{CrashName: "E", CrashGroupID: 7},
+		{CrashReason: "SIGSEGV", Crash: "garbage"},
+	} {
+		grouper.AddCrash(&crash)
+	}
+
+	grouper.AddError(&ExportError{ErrorName: "A"})
+
+	// Error A does not fall into group of crash A.
+	var groups = grouper.Groups()
+	var counts = make(map[string]int)
+
+	for _, group := range groups {
+		counts[group.Name] += group.Count
+	}
+
+	var expected = map[string]int{"A": 2, "B": 1, "C": 2, "D": 2, "SIGSEGV": 1}
+
+	if len(groups) != 6 || !reflect.DeepEqual(counts, expected) {
+		for _, group := range groups {
+			t.Errorf("unexpected group %s: %d", group.Name, group.Count)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package appmetrica
+
+import (
+	"errors"
+	"net"
+	"strconv"
+)
+
+// ValidateTestDevice checks that identifier of test device conforms its type.
+// AppMetrica device identifier is a decimal number while IFA and Google AID
+// are UUIDs.
+func ValidateTestDevice(device *TestDevice) error {
+	switch device.Type {
+	case DT_AppMetricaDeviceID:
+		if _, err := strconv.ParseUint(device.DeviceID, 10, 64); err != nil {
+			var msg = "invalid appmetrica device id: " + device.DeviceID
+			return errors.New(prefix + msg)
+		}
+	case DT_IFA, DT_GoogleAID:
+		if !isUUID(device.DeviceID) {
+			var msg = "invalid advertising id: " + device.DeviceID
+			return errors.New(prefix + msg)
+		}
+	default:
+		var msg = "unknown device id type: " + string(device.Type)
+		return errors.New(prefix + msg)
+	}
+	return nil
+}
+
+// ValidateIPNet checks that network is a valid IPv4 or IPv6 range which has
+// no host bits set (e.g. 10.0.0.1/8 is rejected in favour of 10.0.0.0/8).
+func ValidateIPNet(network *net.IPNet) error {
+	if network == nil {
+		return errors.New(prefix + "ip network is nil")
+	}
+
+	var ones, bits = network.Mask.Size()
+	var valid bool
+
+	// IPv4 address could be stored in 16 bytes but IPv6 address should not
+	// be combined with IPv4 mask.
+	switch bits {
+	case 8 * net.IPv4len:
+		valid = network.IP.To4() != nil
+	case 8 * net.IPv6len:
+		valid = len(network.IP) == net.IPv6len
+	}
+
+	if !valid {
+		var msg = "invalid ip network mask: " + network.String()
+		return errors.New(prefix + msg)
+	}
+
+	if masked := network.IP.Mask(network.Mask); !masked.Equal(network.IP) {
+		var msg = "ip network has host bits set: " + network.IP.String() +
+			"/" + strconv.Itoa(ones)
+		return errors.New(prefix + msg)
+	}
+
+	return nil
+}
+
+// ParseIPFilter parses CIDR and validates it with ValidateIPNet.
+func ParseIPFilter(cidr string) (*net.IPNet, error) {
+	var ip, network, err = net.ParseCIDR(cidr)
+
+	if err != nil {
+		return nil, errors.New(prefix + err.Error())
+	}
+
+	// Restore host bits which are dropped by ParseCIDR in order to validate
+	// network.
+	network.IP = ip
+
+	if err = ValidateIPNet(network); err != nil {
+		return nil, err
+	}
+
+	network.IP = ip.Mask(network.Mask)
+	return network, nil
+}
+
+// isUUID checks that string is a UUID in canonical 8-4-4-4-12 form.
+func isUUID(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+
+	for i, char := range value {
+		switch i {
+		case 8, 13, 18, 23:
+			if char != '-' {
+				return false
+			}
+		default:
+			if !('0' <= char && char <= '9' ||
+				'a' <= char && char <= 'f' ||
+				'A' <= char && char <= 'F') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,92 @@
+package appmetrica
+
+import (
+	"net"
+	"testing"
+)
+
+func TestValidateTestDevice(t *testing.T) {
+	var tests = []struct {
+		Type     DeviceIDType
+		DeviceID string
+		Valid    bool
+	}{
+		{DT_AppMetricaDeviceID, "99999999999999999999", false},
+		{DT_AppMetricaDeviceID, "1234567890123456789", true},
+		{DT_AppMetricaDeviceID, "-1", false},
+		{DT_AppMetricaDeviceID, "0x1f", false},
+		{DT_IFA, "6D92078A-8246-4BA4-AE5B-76104861E7DC", true},
+		{DT_IFA, "6D92078A82464BA4AE5B76104861E7DC", false},
+		{DT_GoogleAID, "38400000-8cf0-11bd-b23e-10b96e40000d", true},
+		{DT_GoogleAID, "38400000-8cf0-11bd-b23e-10b96e40000g", false},
+		{DT_GoogleAID, "1234567890", false},
+		{DeviceIDType("imei"), "490154203237518", false},
+	}
+
+	for _, test := range tests {
+		var err = ValidateTestDevice(&TestDevice{Type: test.Type, DeviceID: test.DeviceID})
+		if test.Valid && err != nil {
+			t.Errorf("valid %s %q was rejected: %s", test.Type, test.DeviceID, err)
+		} else if !test.Valid && err == nil {
+			t.Errorf("invalid %s %q was accepted", test.Type, test.DeviceID)
+		}
+	}
+}
+
+func TestValidateIPNet(t *testing.T) {
+	var tests = []struct {
+		Name    string
+		Network *net.IPNet
+		Valid   bool
+	}{
+		{"Nil", nil, false},
+		{"IPv4", &net.IPNet{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(8, 32)}, true},
+		{"IPv4Short", &net.IPNet{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)}, true},
+		{"HostBits", &net.IPNet{IP: net.IPv4(10, 0, 0, 1), Mask: net.CIDRMask(8, 32)}, false},
+		{"IPv6", &net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)}, true},
+		{"IPv6HostBits", &net.IPNet{IP: net.ParseIP("2001:db8::1"), Mask: net.CIDRMask(32, 128)}, false},
+		{"IPv6WithIPv4Mask", &net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(8, 32)}, false},
+		{"IPv4WithIPv6Mask", &net.IPNet{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 128)}, false},
+		{"NonCanonicalMask", &net.IPNet{IP: net.IPv4(10, 0, 0, 0), Mask: net.IPv4Mask(255, 0, 255, 0)}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var err = ValidateIPNet(test.Network)
+			if test.Valid && err != nil {
+				t.Errorf("valid network was rejected: %s", err)
+			} else if !test.Valid && err == nil {
+				t.Errorf("invalid network was accepted")
+			}
+		})
+	}
+}
+
+func TestParseIPFilter(t *testing.T) {
+	var tests = []struct {
+		CIDR    string
+		Network string
+	}{
+		{"10.0.0.0/8", "10.0.0.0/8"},
+		{"192.168.1.0/24", "192.168.1.0/24"},
+		{"2001:db8::/32", "2001:db8::/32"},
+		{"10.0.0.1/8", ""},
+		{"2001:db8::1/32", ""},
+		{"10.0.0.0/33", ""},
+		{"10.0.0.0", ""},
+	}
+
+	for _, test := range tests {
+		var network, err = ParseIPFilter(test.CIDR)
+
+		if test.Network == "" {
+			if err == nil {
+				t.Errorf("invalid cidr %q was accepted", test.CIDR)
+			}
+		} else if err != nil {
+			t.Errorf("valid cidr %q was rejected: %s", test.CIDR, err)
+		} else if network.String() != test.Network {
+			t.Errorf("wrong network of %q: %s", test.CIDR, network)
+		}
+	}
+}
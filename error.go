@@ -1,7 +1,9 @@
 package appmetrica
 
 import (
+	"context"
 	"errors"
+	"net/http"
 	"strconv"
 )
 
@@ -9,6 +11,30 @@ const prefix = `appmetrica: `
 
 var ErrNotImplemented = errors.New(prefix + "not implemented")
 
+// statusError is an error which is reported by API with status code.
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return prefix + "[" + strconv.Itoa(e.code) + "] " + e.message
+}
+
 func NewError(code int, message string) error {
-	return errors.New(prefix + "[" + strconv.Itoa(code) + "] " + message)
+	return &statusError{code, message}
+}
+
+// retryable reports whether request which failed with err could succeed if it
+// is repeated. Client errors of API except rate limiting are permanent as
+// well as cancellation of context; network and server errors are not.
+func retryable(err error) bool {
+	switch err := err.(type) {
+	case nil:
+		return false
+	case *statusError:
+		return err.code >= http.StatusInternalServerError ||
+			err.code == http.StatusTooManyRequests
+	}
+	return err != context.Canceled && err != context.DeadlineExceeded
 }
@@ -0,0 +1,55 @@
+package appmetrica
+
+import "sort"
+
+// EventNameDiff describes difference between expected catalogue of event
+// names and the one which is known to AppMetrica.
+type EventNameDiff struct {
+	// Missing are expected event names which have never been received.
+	Missing []string
+	// Unexpected are visible event names which are not in catalogue.
+	Unexpected []string
+	// Hidden are expected event names which are hidden from reports.
+	Hidden []string
+}
+
+// Empty reports whether live catalogue matches expected one.
+func (d *EventNameDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Unexpected) == 0 && len(d.Hidden) == 0
+}
+
+// DiffEventNames compares expected event names with live ones which are
+// returned by ListEventNames. Hidden unexpected events are ignored since they
+// are already curated. All lists in result are sorted.
+func DiffEventNames(expected []string, live []EventName) *EventNameDiff {
+	var diff = new(EventNameDiff)
+	var catalogue = make(map[string]struct{}, len(expected))
+	var received = make(map[string]bool, len(live))
+
+	for _, name := range expected {
+		catalogue[name] = struct{}{}
+	}
+
+	for _, event := range live {
+		received[event.Name] = event.Hidden
+
+		if _, ok := catalogue[event.Name]; ok {
+			if event.Hidden {
+				diff.Hidden = append(diff.Hidden, event.Name)
+			}
+		} else if !event.Hidden {
+			diff.Unexpected = append(diff.Unexpected, event.Name)
+		}
+	}
+
+	for name := range catalogue {
+		if _, ok := received[name]; !ok {
+			diff.Missing = append(diff.Missing, name)
+		}
+	}
+
+	sort.Strings(diff.Missing)
+	sort.Strings(diff.Unexpected)
+	sort.Strings(diff.Hidden)
+	return diff
+}
@@ -0,0 +1,44 @@
+package appmetrica
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffEventNames(t *testing.T) {
+	var expected = []string{"purchase", "login", "signup", "logout", "share"}
+	var live = []EventName{
+		{Name: "share", Hidden: true},
+		{Name: "login"},
+		{Name: "tutorial"},
+		{Name: "purchase"},
+		{Name: "debug", Hidden: true},
+		{Name: "crash_test"},
+		{Name: "logout", Hidden: true},
+	}
+
+	var diff = DiffEventNames(expected, live)
+
+	if diff.Empty() {
+		t.Fatalf("diff should not be empty")
+	}
+
+	if missing := []string{"signup"}; !reflect.DeepEqual(diff.Missing, missing) {
+		t.Errorf("wrong missing events: %v", diff.Missing)
+	}
+
+	// Hidden unexpected event debug is ignored.
+	if unexpected := []string{"crash_test", "tutorial"}; !reflect.DeepEqual(diff.Unexpected, unexpected) {
+		t.Errorf("wrong unexpected events: %v", diff.Unexpected)
+	}
+
+	if hidden := []string{"logout", "share"}; !reflect.DeepEqual(diff.Hidden, hidden) {
+		t.Errorf("wrong hidden events: %v", diff.Hidden)
+	}
+
+	var same = DiffEventNames([]string{"login"}, []EventName{{Name: "login"}})
+
+	if !same.Empty() {
+		t.Errorf("diff of the same catalogues should be empty: %+v", same)
+	}
+}
@@ -0,0 +1,317 @@
+package appmetrica
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ExportDateFormat is a layout of date_since and date_until parameters of
+// Logs API.
+const ExportDateFormat = "2006-01-02 15:04:05"
+
+// ExportFormat is a format of Logs API export response body.
+type ExportFormat string
+
+const (
+	EF_CSV  ExportFormat = "csv"
+	EF_JSON ExportFormat = "json"
+)
+
+// DateDimension selects which time of row date range of export applies to.
+type DateDimension string
+
+const (
+	// DD_Default filters rows by time of event on device.
+	DD_Default DateDimension = "default"
+	// DD_Receive filters rows by time when event was received by AppMetrica.
+	DD_Receive DateDimension = "receive"
+)
+
+// OSName is a name of operating system as it is reported in os_name field.
+type OSName string
+
+const (
+	OS_Android      OSName = "android"
+	OS_IOS          OSName = "ios"
+	OS_WindowsPhone OSName = "windowsphone"
+)
+
+// ExportFilter restricts export to rows with specified values of fields.
+// Empty values do not restrict anything.
+type ExportFilter struct {
+	EventName      string
+	OSName         OSName
+	AppVersionName string
+}
+
+// args returns filter as pairs of field and value.
+func (f *ExportFilter) args() [][2]string {
+	var args [][2]string
+
+	if f.EventName != "" {
+		args = append(args, [2]string{"event_name", f.EventName})
+	}
+
+	if f.OSName != "" {
+		args = append(args, [2]string{"os_name", string(f.OSName)})
+	}
+
+	if f.AppVersionName != "" {
+		args = append(args, [2]string{"app_version_name", f.AppVersionName})
+	}
+
+	return args
+}
+
+// ExportResource is a name of Logs API resource which could be exported.
+type ExportResource string
+
+const (
+	ER_Events          ExportResource = "events"
+	ER_Installations   ExportResource = "installations"
+	ER_Clicks          ExportResource = "clicks"
+	ER_Postbacks       ExportResource = "postbacks"
+	ER_SessionsStarts  ExportResource = "sessions_starts"
+	ER_Crashes         ExportResource = "crashes"
+	ER_Errors          ExportResource = "errors"
+	ER_PushTokens      ExportResource = "push_tokens"
+	ER_Profiles        ExportResource = "profiles"
+	ER_DeepLinks       ExportResource = "deeplinks"
+	ER_RevenueEvents   ExportResource = "revenue_events"
+	ER_EcommerceEvents ExportResource = "ecommerce_events"
+	ER_AdRevenueEvents ExportResource = "ad_revenue_events"
+)
+
+// exportRowTypes maps export resources to types of their rows.
+var exportRowTypes = map[ExportResource]reflect.Type{
+	ER_Events:          reflect.TypeOf(ExportEvent{}),
+	ER_Installations:   reflect.TypeOf(ExportInstallation{}),
+	ER_Clicks:          reflect.TypeOf(ExportClick{}),
+	ER_Postbacks:       reflect.TypeOf(ExportPostback{}),
+	ER_SessionsStarts:  reflect.TypeOf(ExportSessionStart{}),
+	ER_Crashes:         reflect.TypeOf(ExportCrash{}),
+	ER_Errors:          reflect.TypeOf(ExportError{}),
+	ER_PushTokens:      reflect.TypeOf(ExportPushToken{}),
+	ER_Profiles:        reflect.TypeOf(ExportProfile{}),
+	ER_DeepLinks:       reflect.TypeOf(ExportDeepLink{}),
+	ER_RevenueEvents:   reflect.TypeOf(ExportRevenueEvent{}),
+	ER_EcommerceEvents: reflect.TypeOf(ExportEcommerceEvent{}),
+	ER_AdRevenueEvents: reflect.TypeOf(ExportAdRevenueEvent{}),
+}
+
+// NewExportRow returns pointer to zero row of resource (e.g. *ExportEvent for
+// events) which could be passed to ExportReader.Scan. It returns nil for
+// unknown resource.
+func NewExportRow(resource ExportResource) interface{} {
+	if typ, ok := exportRowTypes[resource]; ok {
+		return reflect.New(typ).Interface()
+	}
+	return nil
+}
+
+// ExportQuery describes request to export resource (events, installations,
+// clicks, etc) of application from Logs API. Fields are validated against
+// catalogue of known fields before request is sent; use AllFields in order to
+// request all of them.
+//
+// Logs API interprets date range and returns dates in time zone of
+// application. Set Location to Application.Location() in order to convert
+// Since and Until to that time zone and to get correct time of rows; if
+// Location is nil then time zone of Since is used. Filters are passed to API
+// as is; prefer typed Filter for fields it supports.
+type ExportQuery struct {
+	ApplicationID int
+	Resource      ExportResource
+	Fields        []string
+	Since         time.Time
+	Until         time.Time
+	Filter        ExportFilter
+	Filters       map[string]string
+	DateDimension DateDimension
+	Location      *time.Location
+	Format        ExportFormat
+}
+
+// Validate checks that query has all required parameters.
+func (q *ExportQuery) Validate() error {
+	switch {
+	case q.ApplicationID == 0:
+		return errors.New(prefix + "application id is not specified")
+	case q.Resource == "":
+		return errors.New(prefix + "export resource is not specified")
+	case len(q.Fields) == 0:
+		return errors.New(prefix + "export fields are not specified")
+	case q.Since.IsZero() || q.Until.IsZero():
+		return errors.New(prefix + "export date range is not specified")
+	case q.Until.Before(q.Since):
+		return errors.New(prefix + "export date range is empty")
+	}
+
+	switch q.Format {
+	case "", EF_CSV, EF_JSON:
+	default:
+		return errors.New(prefix + "unknown export format: " + string(q.Format))
+	}
+
+	switch q.DateDimension {
+	case "", DD_Default, DD_Receive:
+	default:
+		return errors.New(prefix + "unknown date dimension: " + string(q.DateDimension))
+	}
+
+	switch q.Filter.OSName {
+	case "", OS_Android, OS_IOS, OS_WindowsPhone:
+	default:
+		return errors.New(prefix + "unknown os name: " + string(q.Filter.OSName))
+	}
+
+	for _, arg := range q.Filter.args() {
+		if err := ValidateExportFields(q.Resource, arg[:1]); err != nil {
+			return errors.New(prefix + "invalid filter: " + strings.TrimPrefix(err.Error(), prefix))
+		}
+	}
+
+	return ValidateExportFields(q.Resource, q.fields())
+}
+
+// location returns time zone which date range is expressed in.
+func (q *ExportQuery) location() *time.Location {
+	if q.Location != nil {
+		return q.Location
+	}
+	return q.Since.Location()
+}
+
+// fields returns list of requested fields with AllFields shortcut expanded.
+func (q *ExportQuery) fields() []string {
+	return expandFields(q.Resource, q.Fields)
+}
+
+func (q *ExportQuery) format() ExportFormat {
+	if q.Format == "" {
+		return EF_CSV
+	}
+	return q.Format
+}
+
+func (q *ExportQuery) encode(req *fasthttp.Request) {
+	uri := req.URI()
+	uri.SetPath(`/logs/v1/export/` + string(q.Resource) + `.` + string(q.format()))
+
+	args := uri.QueryArgs()
+	args.Set(`application_id`, strconv.Itoa(q.ApplicationID))
+	args.Set(`date_since`, q.Since.In(q.location()).Format(ExportDateFormat))
+	args.Set(`date_until`, q.Until.In(q.location()).Format(ExportDateFormat))
+	args.Set(`fields`, strings.Join(q.fields(), ","))
+
+	if q.DateDimension != "" {
+		args.Set(`date_dimension`, string(q.DateDimension))
+	}
+
+	for field, value := range q.Filters {
+		args.Set(field, value)
+	}
+
+	for _, arg := range q.Filter.args() {
+		args.Set(arg[0], arg[1])
+	}
+}
+
+// Export выгружает данные ресурса Logs API. Пока AppMetrica готовит данные,
+// API отвечает статусом 202 Accepted; в этом случае запрос повторяется с
+// экспоненциально растущей задержкой до тех пор, пока данные не будут готовы
+// или не истечёт контекст. Пользователь должен закрыть возвращённое тело
+// ответа.
+func (c *Client) Export(ctx context.Context, query *ExportQuery) (io.ReadCloser, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	var delay = c.pollInitial
+
+	for {
+		if err := c.wait(ctx, logsAPI); err != nil {
+			return nil, err
+		}
+
+		var body, retry, err = c.export(ctx, query)
+
+		if err != nil {
+			return nil, err
+		} else if body != nil {
+			return body, nil
+		}
+
+		// Server asks to retry later. Prefer its delay to ours if any.
+		if retry <= 0 {
+			retry = delay
+		}
+
+		var timer = time.NewTimer(retry)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if delay *= 2; delay > c.pollMax {
+			delay = c.pollMax
+		}
+	}
+}
+
+// export makes single request to Logs API. It returns either body of ready
+// export or delay before next poll. The delay is zero if server did not
+// specify it. Body is not buffered and should be closed by caller.
+func (c *Client) export(ctx context.Context, query *ExportQuery) (io.ReadCloser, time.Duration, error) {
+	req, res := c.prepare()
+	query.encode(req)
+
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(res)
+
+	var body, err = c.stream(ctx, req, res)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch status := res.StatusCode(); status {
+	case http.StatusOK:
+		return body, 0, nil
+	case http.StatusAccepted, http.StatusTooManyRequests:
+		body.Close()
+		var retry, _ = strconv.Atoi(string(res.Header.Peek(`Retry-After`)))
+		return nil, time.Duration(retry) * time.Second, nil
+	default:
+		defer body.Close()
+		io.Copy(res.BodyWriter(), io.LimitReader(body, maxErrorBodySize))
+		return nil, 0, c.processError(res)
+	}
+}
+
+// processError converts unsuccessful response to error.
+func (c *Client) processError(res *fasthttp.Response) error {
+	var status = res.StatusCode()
+	var contentType = string(res.Header.Peek(`Content-Type`))
+
+	switch strings.Split(contentType, ";")[0] {
+	case "application/json", "application/x-yametrika+json":
+		if _, err := c.processJSON(res); err != nil {
+			return err
+		}
+		return NewError(status, http.StatusText(status))
+	default:
+		return NewError(status, string(res.Body()))
+	}
+}
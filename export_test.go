@@ -0,0 +1,290 @@
+package appmetrica
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/time/rate"
+)
+
+func TestExportFields(t *testing.T) {
+	t.Run("Catalogue", func(t *testing.T) {
+		for resource, typ := range exportRowTypes {
+			var indices = make(map[string][]int)
+			collectFields(typ, nil, indices)
+
+			for field := range indices {
+				if _, ok := allowedFields[resource][field]; !ok {
+					t.Errorf("field %s of %s row is not in catalogue", field, resource)
+				}
+			}
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		if err := ValidateExportFields(ER_Events, []string{"event_name", "os_name"}); err != nil {
+			t.Errorf("valid fields were rejected: %s", err)
+		}
+
+		err := ValidateExportFields(ER_Events, []string{"evnt_name"})
+		if err == nil || !strings.Contains(err.Error(), "`event_name`") {
+			t.Errorf("wrong suggestion for misspelled field: %v", err)
+		}
+
+		if err := ValidateExportFields(ER_Profiles, []string{"loyalty_level"}); err != nil {
+			t.Errorf("custom profile attribute was rejected: %s", err)
+		}
+
+		for field, suggestion := range map[string]string{
+			"profile_idd":        "profile_id",
+			"appmetrica_nme":     "appmetrica_name",
+			"appmetirca_crashes": "appmetrica_crashes",
+		} {
+			err := ValidateExportFields(ER_Profiles, []string{field})
+			if err == nil || !strings.Contains(err.Error(), "`"+suggestion+"`") {
+				t.Errorf("wrong suggestion for misspelled profile field %s: %v", field, err)
+			}
+		}
+
+		if err := ValidateExportFields("sessions", []string{"session_id"}); err == nil {
+			t.Errorf("unknown resource was accepted")
+		}
+	})
+
+	t.Run("Complete", func(t *testing.T) {
+		fields := CompleteExportField(ER_Events, "event_r")
+		if strings.Join(fields, ",") != "event_receive_datetime,event_receive_timestamp" {
+			t.Errorf("wrong completion: %v", fields)
+		}
+	})
+
+	t.Run("AllFields", func(t *testing.T) {
+		query := &ExportQuery{
+			ApplicationID: 84126,
+			Resource:      ER_Clicks,
+			Fields:        []string{AllFields},
+			Since:         time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+			Until:         time.Date(2018, 8, 2, 0, 0, 0, 0, time.UTC),
+		}
+
+		if err := query.Validate(); err != nil {
+			t.Fatalf("valid query was rejected: %s", err)
+		}
+
+		if fields := query.fields(); len(fields) != len(exportFields[ER_Clicks]) {
+			t.Errorf("wrong number of fields: %d", len(fields))
+		}
+	})
+}
+
+func TestExportWindow(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*3600)
+	since := time.Date(2018, 8, 1, 12, 0, 0, 0, loc)
+	until := time.Date(2018, 8, 3, 6, 0, 0, 0, loc)
+
+	windows := EW_Day.split(since, until)
+
+	if len(windows) != 3 {
+		t.Fatalf("wrong number of daily windows: %d", len(windows))
+	}
+
+	if !windows[0][0].Equal(since) || windows[0][1].Format(ExportDateFormat) != "2018-08-01 23:59:59" {
+		t.Errorf("wrong first window: %v", windows[0])
+	}
+
+	if windows[1][0].Format(ExportDateFormat) != "2018-08-02 00:00:00" {
+		t.Errorf("wrong second window: %v", windows[1])
+	}
+
+	if !windows[2][1].Equal(until) {
+		t.Errorf("wrong last window: %v", windows[2])
+	}
+
+	if windows := EW_Hour.split(since, until); len(windows) != 43 {
+		t.Errorf("wrong number of hourly windows: %d", len(windows))
+	}
+}
+
+func TestExportQuery(t *testing.T) {
+	app := &Application{TimeZoneName: "Europe/Moscow"}
+	loc, err := app.Location()
+	if err != nil {
+		t.Skipf("time zone database is not available: %s", err)
+	}
+
+	query := &ExportQuery{
+		ApplicationID: 84126,
+		Resource:      ER_Events,
+		Fields:        []string{"event_name", "event_datetime"},
+		Since:         time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2018, 8, 1, 20, 59, 59, 0, time.UTC),
+		Filter:        ExportFilter{EventName: "launch", OSName: OS_IOS},
+		DateDimension: DD_Receive,
+		Location:      loc,
+	}
+
+	if err := query.Validate(); err != nil {
+		t.Fatalf("valid query was rejected: %s", err)
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	query.encode(req)
+
+	args := req.URI().QueryArgs()
+	expected := map[string]string{
+		"date_since":     "2018-08-01 03:00:00",
+		"date_until":     "2018-08-01 23:59:59",
+		"date_dimension": "receive",
+		"event_name":     "launch",
+		"os_name":        "ios",
+	}
+
+	for arg, value := range expected {
+		if actual := string(args.Peek(arg)); actual != value {
+			t.Errorf("wrong value of %s: %q != %q", arg, actual, value)
+		}
+	}
+
+	query.Resource = ER_Installations
+	query.Fields = []string{"install_datetime"}
+
+	if err := query.Validate(); err == nil {
+		t.Errorf("filter by event name of installations was accepted")
+	}
+}
+
+func TestExport(t *testing.T) {
+	var mutex sync.Mutex
+	var times []time.Time
+	var statuses []int
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		times = append(times, time.Now())
+
+		if len(statuses) == 0 {
+			w.Write([]byte("event_name\nlaunch\n"))
+			return
+		}
+
+		var status = statuses[0]
+		statuses = statuses[1:]
+
+		switch status {
+		case http.StatusTooManyRequests:
+			w.Header().Set("Retry-After", "1")
+		case http.StatusBadRequest:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"code": 400, "message": "invalid fields"}`))
+			return
+		}
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.limiters[logsAPI] = rate.NewLimiter(rate.Inf, 1)
+	client.SetExportPolling(20*time.Millisecond, 30*time.Millisecond)
+
+	query := &ExportQuery{
+		ApplicationID: 84126,
+		Resource:      ER_Events,
+		Fields:        []string{"event_name"},
+		Since:         time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2018, 8, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	var reset = func(codes ...int) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		times, statuses = nil, codes
+	}
+
+	var export = func(ctx context.Context) (string, error) {
+		body, err := client.Export(ctx, query)
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		data, err := ioutil.ReadAll(body)
+		return string(data), err
+	}
+
+	t.Run("Poll", func(t *testing.T) {
+		var accepted = http.StatusAccepted
+		reset(accepted, accepted, accepted, accepted, accepted)
+
+		data, err := export(context.Background())
+		if err != nil {
+			t.Fatalf("failed to export: %s", err)
+		}
+
+		if data != "event_name\nlaunch\n" {
+			t.Errorf("unexpected export: %q", data)
+		}
+
+		if len(times) != 6 {
+			t.Fatalf("expected 6 requests instead of %d", len(times))
+		}
+
+		// Delays are 20ms, 30ms, 30ms, 30ms and 30ms; they would reach 320ms
+		// without maximal delay.
+		for i := 1; i < len(times); i++ {
+			if delay := times[i].Sub(times[i-1]); delay < 20*time.Millisecond || delay > 250*time.Millisecond {
+				t.Errorf("unexpected delay before poll #%d: %s", i, delay)
+			}
+		}
+	})
+
+	t.Run("RetryAfter", func(t *testing.T) {
+		reset(http.StatusTooManyRequests)
+
+		if _, err := export(context.Background()); err != nil {
+			t.Fatalf("failed to export: %s", err)
+		}
+
+		if len(times) != 2 {
+			t.Fatalf("expected 2 requests instead of %d", len(times))
+		}
+
+		if delay := times[1].Sub(times[0]); delay < time.Second {
+			t.Errorf("delay of Retry-After is ignored: %s", delay)
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		var accepted = http.StatusAccepted
+		reset(accepted, accepted, accepted, accepted, accepted, accepted, accepted, accepted)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		if _, err := export(ctx); err != context.DeadlineExceeded {
+			t.Errorf("export should be stopped by context: %v", err)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		reset(http.StatusBadRequest)
+
+		if _, err := export(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid fields") {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		if len(times) != 1 {
+			t.Errorf("failed export should not be retried: %d requests", len(times))
+		}
+	})
+}
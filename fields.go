@@ -0,0 +1,253 @@
+package appmetrica
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// AllFields is a shortcut which selects all known fields of export resource
+// if it is passed as the only element of ExportQuery.Fields.
+const AllFields = "*"
+
+// exportFields is a catalogue of valid fields of export resources in order
+// which is used for AllFields selection.
+var exportFields map[ExportResource][]string
+
+// allowedFields contains the same fields as exportFields for fast lookup.
+var allowedFields map[ExportResource]map[string]struct{}
+
+func init() {
+	var device = []string{
+		"appmetrica_device_id", "ios_ifa", "ios_ifv", "google_aid",
+		"windows_aid", "os_name", "os_version", "device_manufacturer",
+		"device_model", "device_type", "device_locale", "app_version_name",
+		"app_package_name", "connection_type", "operator_name", "mcc", "mnc",
+		"country_iso_code", "city",
+	}
+
+	var click = []string{
+		"click_id", "click_datetime", "click_timestamp", "click_ipv6",
+		"click_url_parameters", "click_user_agent", "tracking_id",
+		"tracker_name", "publisher_id", "publisher_name",
+	}
+
+	var event = []string{
+		"event_datetime", "event_timestamp", "event_receive_datetime",
+		"event_receive_timestamp",
+	}
+
+	var join = func(groups ...[]string) []string {
+		var fields = []string{"application_id"}
+		for _, group := range groups {
+			fields = append(fields, group...)
+		}
+		return fields
+	}
+
+	exportFields = map[ExportResource][]string{
+		ER_Events: join(device, event, []string{
+			"profile_id", "event_name", "event_json", "session_id",
+		}),
+		ER_Installations: join(device, click, []string{
+			"profile_id", "install_datetime", "install_timestamp",
+			"install_receive_datetime", "install_receive_timestamp",
+			"install_ipv6", "is_reinstallation", "is_reattribution",
+			"match_type",
+		}),
+		ER_Clicks: join(device, click),
+		ER_Postbacks: join(device, click, []string{
+			"attributed_touch_type", "conversion_datetime",
+			"conversion_timestamp", "event_name", "install_datetime",
+			"install_timestamp", "notifying_status", "postback_url",
+			"postback_url_parameters", "response_body", "response_code",
+		}),
+		ER_SessionsStarts: join(device, []string{
+			"profile_id", "session_id", "session_start_datetime",
+			"session_start_timestamp", "session_start_receive_datetime",
+			"session_start_receive_timestamp",
+		}),
+		ER_Crashes: join(device, []string{
+			"profile_id", "crash", "crash_id", "crash_group_id", "crash_name",
+			"crash_reason", "crash_reason_message", "crash_binary_name",
+			"crash_file_name", "crash_method_name", "crash_source_line",
+			"crash_datetime", "crash_timestamp", "crash_receive_datetime",
+			"crash_receive_timestamp",
+		}),
+		ER_Errors: join(device, []string{
+			"profile_id", "error", "error_id", "error_name", "error_datetime",
+			"error_timestamp", "error_receive_datetime",
+			"error_receive_timestamp",
+		}),
+		ER_PushTokens: join(device, []string{
+			"profile_id", "token", "token_datetime", "token_timestamp",
+			"token_receive_datetime", "token_receive_timestamp",
+		}),
+		ER_Profiles: join([]string{
+			"profile_id", "appmetrica_device_id", "appmetrica_name",
+			"appmetrica_gender", "appmetrica_birth_date",
+			"appmetrica_notifications_enabled", "appmetrica_crashes",
+			"appmetrica_errors", "appmetrica_sessions",
+			"appmetrica_push_opens", "appmetrica_push_send_count",
+			"appmetrica_first_session_date", "appmetrica_last_start_date",
+			"appmetrica_sdk_version",
+		}),
+		ER_DeepLinks: join(device, []string{
+			"profile_id", "deeplink_url_scheme", "deeplink_url_host",
+			"deeplink_url_path", "deeplink_url_parameters",
+			"deeplink_datetime", "deeplink_timestamp",
+			"deeplink_receive_datetime", "deeplink_receive_timestamp",
+			"is_reengagement", "tracking_id", "tracker_name", "publisher_id",
+			"publisher_name",
+		}),
+		ER_RevenueEvents: join(device, event, []string{
+			"profile_id", "revenue_price", "revenue_currency",
+			"revenue_quantity", "revenue_product_id", "revenue_order_id",
+			"revenue_order_id_source", "is_revenue_verified",
+		}),
+		ER_EcommerceEvents: join(device, event, []string{
+			"profile_id", "ecom_type", "ecom_content",
+		}),
+		ER_AdRevenueEvents: join(device, event, []string{
+			"profile_id", "ad_revenue_value", "ad_revenue_currency",
+			"ad_revenue_ad_type", "ad_revenue_ad_network",
+			"ad_revenue_ad_unit_id", "ad_revenue_ad_unit_name",
+			"ad_revenue_ad_placement_id", "ad_revenue_precision",
+			"ad_revenue_payload",
+		}),
+	}
+
+	allowedFields = make(map[ExportResource]map[string]struct{}, len(exportFields))
+
+	for resource, fields := range exportFields {
+		allowedFields[resource] = make(map[string]struct{}, len(fields))
+
+		for _, field := range fields {
+			allowedFields[resource][field] = struct{}{}
+		}
+	}
+}
+
+// ExportFields returns all known fields of export resource. It returns nil
+// for unknown resource.
+func ExportFields(resource ExportResource) []string {
+	return append([]string(nil), exportFields[resource]...)
+}
+
+// CompleteExportField returns sorted list of known fields of export resource
+// which start with partial field name.
+func CompleteExportField(resource ExportResource, partial string) []string {
+	var fields []string
+
+	for _, field := range exportFields[resource] {
+		if strings.HasPrefix(field, partial) {
+			fields = append(fields, field)
+		}
+	}
+
+	sort.Strings(fields)
+	return fields
+}
+
+// maxProfileFieldDistance is an edit distance to known field of profiles
+// within which unknown field is considered misspelled rather than custom
+// attribute.
+const maxProfileFieldDistance = 2
+
+// ValidateExportFields checks that all fields are known for export resource.
+// Custom attributes of profiles are not known in advance so that unknown
+// field of profiles without `appmetrica_` prefix is considered valid unless
+// it is close to a known field. Error message contains the closest known
+// field for misspelled one.
+func ValidateExportFields(resource ExportResource, fields []string) error {
+	var allowed, ok = allowedFields[resource]
+
+	if !ok {
+		return errors.New(prefix + "unknown export resource: " + string(resource))
+	}
+
+	for _, field := range fields {
+		if _, ok := allowed[field]; ok {
+			continue
+		}
+
+		var suggestion = suggestExportField(resource, field)
+
+		if resource == ER_Profiles && !strings.HasPrefix(field, "appmetrica_") {
+			if suggestion == "" || editDistance(field, suggestion) > maxProfileFieldDistance {
+				continue
+			}
+		}
+
+		var msg = "unknown field `" + field + "` of " + string(resource)
+
+		if suggestion != "" {
+			msg += "; did you mean `" + suggestion + "`?"
+		}
+
+		return errors.New(prefix + msg)
+	}
+
+	return nil
+}
+
+// expandFields replaces AllFields shortcut with full list of fields.
+func expandFields(resource ExportResource, fields []string) []string {
+	if len(fields) == 1 && fields[0] == AllFields {
+		return ExportFields(resource)
+	}
+	return fields
+}
+
+// suggestExportField returns known field which is the closest to the given
+// one in terms of edit distance or empty string if there is no close field.
+func suggestExportField(resource ExportResource, field string) string {
+	var suggestion string
+	var best = len(field)/3 + 1 // Do not suggest completely different fields.
+
+	for _, known := range exportFields[resource] {
+		var distance = editDistance(field, known)
+
+		if distance < best || distance == best && suggestion == "" {
+			suggestion, best = known, distance
+		}
+	}
+
+	return suggestion
+}
+
+// editDistance computes Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	var prev = make([]int, len(b)+1)
+	var curr = make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			var cost = 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j-1] + cost
+
+			if curr[j] > prev[j]+1 {
+				curr[j] = prev[j] + 1
+			}
+
+			if curr[j] > curr[j-1]+1 {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,489 @@
+package appmetrica
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FilterOp is a comparison operator of Reporting API filter expression.
+type FilterOp string
+
+const (
+	FO_Eq          FilterOp = "=="
+	FO_Ne          FilterOp = "!="
+	FO_Gt          FilterOp = ">"
+	FO_Ge          FilterOp = ">="
+	FO_Lt          FilterOp = "<"
+	FO_Le          FilterOp = "<="
+	FO_In          FilterOp = "=."
+	FO_NotIn       FilterOp = "!."
+	FO_Like        FilterOp = "=*"
+	FO_NotLike     FilterOp = "!*"
+	FO_Contains    FilterOp = "=@"
+	FO_NotContains FilterOp = "!@"
+	FO_Regexp      FilterOp = "=~"
+	FO_NotRegexp   FilterOp = "!~"
+)
+
+// filterOps lists operators so that two-character ones go before their
+// one-character prefixes.
+var filterOps = []FilterOp{
+	FO_Eq, FO_Ne, FO_Ge, FO_Le, FO_In, FO_NotIn, FO_Like, FO_NotLike,
+	FO_Contains, FO_NotContains, FO_Regexp, FO_NotRegexp, FO_Gt, FO_Lt,
+}
+
+// Filter is a node of Reporting API filter expression. String renders node
+// in filter syntax which could be passed as ReportQuery.Filters.
+type Filter interface {
+	String() string
+	filter()
+}
+
+// Condition compares dimension or metric with values. Values are either
+// strings or float64 numbers; operators other than FO_In and FO_NotIn take
+// exactly one value.
+type Condition struct {
+	Field  string
+	Op     FilterOp
+	Values []interface{}
+}
+
+// AndFilter matches if all of its filters match.
+type AndFilter struct {
+	Filters []Filter
+}
+
+// OrFilter matches if any of its filters matches.
+type OrFilter struct {
+	Filters []Filter
+}
+
+// NotFilter matches if its filter does not match.
+type NotFilter struct {
+	Filter Filter
+}
+
+func (*Condition) filter() {}
+func (*AndFilter) filter() {}
+func (*OrFilter) filter()  {}
+func (*NotFilter) filter() {}
+
+func (c *Condition) String() string {
+	var values = make([]string, len(c.Values))
+
+	for i, value := range c.Values {
+		values[i] = formatFilterValue(value)
+	}
+
+	if c.Op == FO_In || c.Op == FO_NotIn {
+		return c.Field + string(c.Op) + "(" + strings.Join(values, ",") + ")"
+	}
+
+	return c.Field + string(c.Op) + strings.Join(values, ",")
+}
+
+func (f *AndFilter) String() string {
+	return joinFilters(f.Filters, " AND ")
+}
+
+func (f *OrFilter) String() string {
+	return joinFilters(f.Filters, " OR ")
+}
+
+func (f *NotFilter) String() string {
+	var expr = f.Filter.String()
+
+	if expr == "" {
+		return ""
+	}
+
+	return "NOT(" + expr + ")"
+}
+
+// joinFilters renders filters with operator. Nested compound filters are
+// parenthesized so that expression is parsed back into the same tree. Empty
+// filters are skipped.
+func joinFilters(filters []Filter, operator string) string {
+	var parts = make([]string, 0, len(filters))
+
+	for _, filter := range filters {
+		var expr = filter.String()
+
+		switch {
+		case expr == "":
+			continue
+		case isCompoundFilter(filter):
+			parts = append(parts, "("+expr+")")
+		default:
+			parts = append(parts, expr)
+		}
+	}
+
+	return strings.Join(parts, operator)
+}
+
+func isCompoundFilter(filter Filter) bool {
+	switch filter.(type) {
+	case *AndFilter, *OrFilter:
+		return true
+	default:
+		return false
+	}
+}
+
+// formatFilterValue renders number as is and anything else as quoted string
+// with quotes and backslashes escaped.
+func formatFilterValue(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+
+	var text = fmt.Sprint(value)
+	text = strings.Replace(text, `\`, `\\`, -1)
+	text = strings.Replace(text, `'`, `\'`, -1)
+	return "'" + text + "'"
+}
+
+// normalizeFilterValue converts numbers of any type to float64 and other
+// values to strings.
+func normalizeFilterValue(value interface{}) interface{} {
+	if text, ok := value.(string); ok {
+		return text
+	}
+
+	var v = reflect.ValueOf(value)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func newCondition(field string, op FilterOp, values ...interface{}) *Condition {
+	var condition = &Condition{Field: field, Op: op, Values: make([]interface{}, len(values))}
+
+	for i, value := range values {
+		condition.Values[i] = normalizeFilterValue(value)
+	}
+
+	return condition
+}
+
+// Eq matches rows where field equals value.
+func Eq(field string, value interface{}) Filter {
+	return newCondition(field, FO_Eq, value)
+}
+
+// Ne matches rows where field does not equal value.
+func Ne(field string, value interface{}) Filter {
+	return newCondition(field, FO_Ne, value)
+}
+
+// Gt matches rows where field is greater than value.
+func Gt(field string, value interface{}) Filter {
+	return newCondition(field, FO_Gt, value)
+}
+
+// Ge matches rows where field is greater than or equal to value.
+func Ge(field string, value interface{}) Filter {
+	return newCondition(field, FO_Ge, value)
+}
+
+// Lt matches rows where field is less than value.
+func Lt(field string, value interface{}) Filter {
+	return newCondition(field, FO_Lt, value)
+}
+
+// Le matches rows where field is less than or equal to value.
+func Le(field string, value interface{}) Filter {
+	return newCondition(field, FO_Le, value)
+}
+
+// In matches rows where field equals one of values.
+func In(field string, values ...interface{}) Filter {
+	return newCondition(field, FO_In, values...)
+}
+
+// Like matches rows where field matches pattern with `*` wildcards.
+func Like(field string, pattern string) Filter {
+	return newCondition(field, FO_Like, pattern)
+}
+
+// And matches rows which match all filters. And and Or without filters (as
+// well as Not of them) render empty expression which means no filtering;
+// they are skipped inside other filters.
+func And(filters ...Filter) Filter {
+	return &AndFilter{Filters: filters}
+}
+
+// Or matches rows which match any of filters.
+func Or(filters ...Filter) Filter {
+	return &OrFilter{Filters: filters}
+}
+
+// Not matches rows which do not match filter.
+func Not(filter Filter) Filter {
+	return &NotFilter{Filter: filter}
+}
+
+// ParseFilter parses filter expression of Reporting API. AND binds tighter
+// than OR; keywords are case insensitive.
+func ParseFilter(expr string) (Filter, error) {
+	var parser = &filterParser{input: expr}
+	var filter, err = parser.parseOr()
+
+	if err == nil {
+		if parser.skipSpaces(); parser.pos < len(parser.input) {
+			err = parser.errorf("unexpected input")
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// filterParser is a recursive descent parser of filter expressions.
+type filterParser struct {
+	input string
+	pos   int
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	var msg = fmt.Sprintf(format, args...)
+	return errors.New(prefix + "invalid filter at " + strconv.Itoa(p.pos) + ": " + msg)
+}
+
+func (p *filterParser) skipSpaces() {
+	for p.pos < len(p.input) && strings.IndexByte(" \t\r\n", p.input[p.pos]) != -1 {
+		p.pos++
+	}
+}
+
+// keyword consumes keyword if it is the next token.
+func (p *filterParser) keyword(word string) bool {
+	p.skipSpaces()
+
+	var end = p.pos + len(word)
+
+	if end > len(p.input) || !strings.EqualFold(p.input[p.pos:end], word) {
+		return false
+	}
+
+	// Keyword should not be a prefix of field name.
+	if end < len(p.input) && isFieldChar(p.input[end]) {
+		return false
+	}
+
+	p.pos = end
+	return true
+}
+
+// char consumes character if it is the next token.
+func (p *filterParser) char(c byte) bool {
+	p.skipSpaces()
+
+	if p.pos < len(p.input) && p.input[p.pos] == c {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	var filters []Filter
+
+	for {
+		var filter, err = p.parseAnd()
+
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+
+		if !p.keyword("OR") {
+			break
+		}
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+
+	return &OrFilter{Filters: filters}, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	var filters []Filter
+
+	for {
+		var filter, err = p.parseUnary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+
+		if !p.keyword("AND") {
+			break
+		}
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+
+	return &AndFilter{Filters: filters}, nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	var negate = p.keyword("NOT")
+
+	if negate || p.char('(') {
+		if negate && !p.char('(') {
+			return nil, p.errorf("expected `(` after NOT")
+		}
+
+		var filter, err = p.parseOr()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.char(')') {
+			return nil, p.errorf("expected `)`")
+		}
+
+		if negate {
+			filter = &NotFilter{Filter: filter}
+		}
+
+		return filter, nil
+	}
+
+	return p.parseCondition()
+}
+
+func (p *filterParser) parseCondition() (Filter, error) {
+	p.skipSpaces()
+
+	var start = p.pos
+
+	for p.pos < len(p.input) && isFieldChar(p.input[p.pos]) {
+		p.pos++
+	}
+
+	if start == p.pos {
+		return nil, p.errorf("expected field name")
+	}
+
+	var condition = &Condition{Field: p.input[start:p.pos]}
+
+	p.skipSpaces()
+
+	for _, op := range filterOps {
+		if strings.HasPrefix(p.input[p.pos:], string(op)) {
+			condition.Op = op
+			p.pos += len(op)
+			break
+		}
+	}
+
+	if condition.Op == "" {
+		return nil, p.errorf("expected operator")
+	}
+
+	if condition.Op == FO_In || condition.Op == FO_NotIn {
+		if !p.char('(') {
+			return nil, p.errorf("expected `(` after %s", condition.Op)
+		}
+
+		for {
+			var value, err = p.parseValue()
+
+			if err != nil {
+				return nil, err
+			}
+
+			condition.Values = append(condition.Values, value)
+
+			if !p.char(',') {
+				break
+			}
+		}
+
+		if !p.char(')') {
+			return nil, p.errorf("expected `)`")
+		}
+
+		return condition, nil
+	}
+
+	var value, err = p.parseValue()
+
+	if err != nil {
+		return nil, err
+	}
+
+	condition.Values = []interface{}{value}
+	return condition, nil
+}
+
+// parseValue parses quoted string or number.
+func (p *filterParser) parseValue() (interface{}, error) {
+	p.skipSpaces()
+
+	if p.pos < len(p.input) && p.input[p.pos] == '\'' {
+		var value []byte
+
+		for p.pos++; p.pos < len(p.input); p.pos++ {
+			switch c := p.input[p.pos]; c {
+			case '\\':
+				if p.pos++; p.pos < len(p.input) {
+					value = append(value, p.input[p.pos])
+				}
+			case '\'':
+				p.pos++
+				return string(value), nil
+			default:
+				value = append(value, c)
+			}
+		}
+
+		return nil, p.errorf("unterminated string")
+	}
+
+	var start = p.pos
+
+	for p.pos < len(p.input) && strings.IndexByte("+-.0123456789eE", p.input[p.pos]) != -1 {
+		p.pos++
+	}
+
+	var number, err = strconv.ParseFloat(p.input[start:p.pos], 64)
+
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("expected string or number")
+	}
+
+	return number, nil
+}
+
+func isFieldChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == ':' || c == '_' || c == '.'
+}
@@ -0,0 +1,78 @@
+package appmetrica
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	filter := And(
+		Eq("ym:ge:operatingSystem", "android"),
+		Or(
+			In("ym:ge:appVersion", "1.0", "1.1"),
+			Like("ym:ge:mobileDeviceModel", "Galaxy*"),
+		),
+		Not(Gt("ym:ge:sessions", 10)),
+		Eq("ym:ge:regionCity", `O'Fallon \ MO`),
+	)
+
+	expected := `ym:ge:operatingSystem=='android' AND ` +
+		`(ym:ge:appVersion=.('1.0','1.1') OR ym:ge:mobileDeviceModel=*'Galaxy*') AND ` +
+		`NOT(ym:ge:sessions>10) AND ym:ge:regionCity=='O\'Fallon \\ MO'`
+
+	if actual := filter.String(); actual != expected {
+		t.Fatalf("wrong rendering of filter:\n%s\n%s", actual, expected)
+	}
+
+	parsed, err := ParseFilter(expected)
+	if err != nil {
+		t.Fatalf("failed to parse filter: %s", err)
+	}
+
+	if !reflect.DeepEqual(parsed, filter) {
+		t.Errorf("parsed filter differs from built one: %s", parsed)
+	}
+
+	parsed, err = ParseFilter("a==1 or b!='x' and not (c<=2.5)")
+	if err != nil {
+		t.Fatalf("failed to parse filter: %s", err)
+	}
+
+	if actual := parsed.String(); actual != "a==1 OR (b!='x' AND NOT(c<=2.5))" {
+		t.Errorf("wrong precedence of operators: %s", actual)
+	}
+
+	for _, invalid := range []string{"", "a==", "a=='x", "(a==1", "a==1 b==2", "a=.('x'"} {
+		if _, err := ParseFilter(invalid); err == nil {
+			t.Errorf("invalid filter %q was parsed", invalid)
+		}
+	}
+}
+
+func TestFilterValues(t *testing.T) {
+	type count uint16
+
+	var tests = []struct {
+		Filter   Filter
+		Expected string
+	}{
+		{Gt("x", int8(-5)), "x>-5"},
+		{Gt("x", int16(5)), "x>5"},
+		{Gt("x", uint8(5)), "x>5"},
+		{Gt("x", uint16(5)), "x>5"},
+		{Gt("x", count(5)), "x>5"},
+		{Gt("x", float32(0.5)), "x>0.5"},
+		{Eq("x", Dimension("y")), "x=='y'"},
+		{Eq("x", true), "x=='true'"},
+		{And(), ""},
+		{Or(And(), Not(Or())), ""},
+		{And(Eq("x", 1), Or(), Eq("y", 2)), "x==1 AND y==2"},
+		{Or(And(Eq("x", 1)), And()), "(x==1)"},
+	}
+
+	for _, test := range tests {
+		if actual := test.Filter.String(); actual != test.Expected {
+			t.Errorf("wrong rendering of filter: %q != %q", actual, test.Expected)
+		}
+	}
+}
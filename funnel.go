@@ -0,0 +1,236 @@
+package appmetrica
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// FunnelKey selects identifier which events of one user are grouped by.
+type FunnelKey int
+
+const (
+	FK_Device FunnelKey = iota
+	FK_Profile
+)
+
+// Funnel describes ordered sequence of events. User reaches step N if events
+// of steps 0..N happened in this order and the last of them happened within
+// Window after the first one. Zero Window means no limit.
+type Funnel struct {
+	Steps  []string
+	Window time.Duration
+	Key    FunnelKey
+}
+
+// FunnelStep is a statistics of funnel step. Conversion is a share of users
+// of the previous step who reached this one; TotalConversion is a share of
+// users of the first step. MedianTime is a median time between the previous
+// step and this one (zero for the first step).
+type FunnelStep struct {
+	EventName       string
+	Count           int
+	Conversion      float64
+	TotalConversion float64
+	MedianTime      time.Duration
+}
+
+// FunnelResult is a statistics of every step of funnel.
+type FunnelResult struct {
+	Steps []FunnelStep
+}
+
+// funnelEvent is an event of user which is relevant to funnel.
+type funnelEvent struct {
+	at   time.Time
+	name string
+}
+
+// FunnelEngine computes funnel from stream of events. Events could come in
+// any order; only events of funnel steps are kept in memory.
+type FunnelEngine struct {
+	funnel Funnel
+	steps  map[string]struct{}
+	users  map[string][]funnelEvent
+}
+
+// NewFunnelEngine creates engine for funnel.
+func NewFunnelEngine(funnel Funnel) (*FunnelEngine, error) {
+	if len(funnel.Steps) == 0 {
+		return nil, errors.New(prefix + "funnel has no steps")
+	}
+
+	var engine = &FunnelEngine{
+		funnel: funnel,
+		steps:  make(map[string]struct{}, len(funnel.Steps)),
+		users:  make(map[string][]funnelEvent),
+	}
+
+	for _, step := range funnel.Steps {
+		engine.steps[step] = struct{}{}
+	}
+
+	return engine, nil
+}
+
+// Add adds event to funnel. Events without user identifier or time are
+// ignored.
+func (e *FunnelEngine) Add(event *ExportEvent) {
+	if _, ok := e.steps[event.EventName]; !ok {
+		return
+	}
+
+	var user string
+
+	switch e.funnel.Key {
+	case FK_Profile:
+		user = event.ProfileID
+	default:
+		if event.DeviceID != 0 {
+			user = strconv.FormatUint(event.DeviceID, 10)
+		}
+	}
+
+	var at = event.EventDatetime
+
+	if at.IsZero() && event.EventTimestamp != 0 {
+		at = time.Unix(event.EventTimestamp, 0)
+	}
+
+	if user == "" || at.IsZero() {
+		return
+	}
+
+	e.users[user] = append(e.users[user], funnelEvent{at, event.EventName})
+}
+
+// Result computes statistics of funnel over events added so far.
+func (e *FunnelEngine) Result() *FunnelResult {
+	var steps = e.funnel.Steps
+	var counts = make([]int, len(steps))
+	var durations = make([][]time.Duration, len(steps))
+
+	for _, events := range e.users {
+		sort.SliceStable(events, func(i, j int) bool {
+			return events[i].at.Before(events[j].at)
+		})
+
+		var times = e.progress(events)
+
+		for step := range times {
+			counts[step]++
+			if step > 0 {
+				durations[step] = append(durations[step], times[step].Sub(times[step-1]))
+			}
+		}
+	}
+
+	var result = &FunnelResult{Steps: make([]FunnelStep, len(steps))}
+
+	for i, name := range steps {
+		var step = &result.Steps[i]
+		step.EventName = name
+		step.Count = counts[i]
+		step.MedianTime = medianDuration(durations[i])
+
+		if counts[0] > 0 {
+			step.TotalConversion = float64(counts[i]) / float64(counts[0])
+		}
+
+		if i == 0 && counts[0] > 0 {
+			step.Conversion = 1
+		} else if i > 0 && counts[i-1] > 0 {
+			step.Conversion = float64(counts[i]) / float64(counts[i-1])
+		}
+	}
+
+	return result
+}
+
+// progress returns times of steps of the deepest path through funnel in
+// time ordered events of user. Every occurrence of the first step is tried as
+// start of path; the next steps are matched greedily.
+func (e *FunnelEngine) progress(events []funnelEvent) []time.Time {
+	var steps = e.funnel.Steps
+	var best []time.Time
+
+	for start := range events {
+		if events[start].name != steps[0] {
+			continue
+		}
+
+		var times = []time.Time{events[start].at}
+		var deadline = events[start].at.Add(e.funnel.Window)
+
+		for _, event := range events[start+1:] {
+			if len(times) == len(steps) {
+				break
+			}
+
+			if e.funnel.Window > 0 && event.at.After(deadline) {
+				break
+			}
+
+			if event.name == steps[len(times)] {
+				times = append(times, event.at)
+			}
+		}
+
+		if len(times) > len(best) {
+			best = times
+		}
+
+		if len(best) == len(steps) {
+			break
+		}
+	}
+
+	return best
+}
+
+// medianDuration returns median of durations; durations are reordered.
+func medianDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+
+	var middle = len(durations) / 2
+
+	if len(durations)%2 == 0 {
+		return (durations[middle-1] + durations[middle]) / 2
+	}
+
+	return durations[middle]
+}
+
+// ComputeFunnel reads events export and computes funnel. Export should
+// contain event_name, event_datetime (or event_timestamp) and identifier
+// field of funnel key. Reader is not closed.
+func ComputeFunnel(reader *ExportReader, funnel Funnel) (*FunnelResult, error) {
+	var engine, err = NewFunnelEngine(funnel)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for reader.Next() {
+		var event ExportEvent
+
+		if err = reader.Scan(&event); err != nil {
+			return nil, err
+		}
+
+		engine.Add(&event)
+	}
+
+	if err = reader.Err(); err != nil {
+		return nil, err
+	}
+
+	return engine.Result(), nil
+}
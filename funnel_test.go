@@ -0,0 +1,49 @@
+package appmetrica
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFunnel(t *testing.T) {
+	body := `appmetrica_device_id,event_name,event_datetime
+1,open,2018-08-01 10:00:00
+1,cart,2018-08-01 10:02:00
+1,buy,2018-08-01 10:10:00
+2,cart,2018-08-01 09:00:00
+2,open,2018-08-01 10:00:00
+2,cart,2018-08-01 10:04:00
+3,open,2018-08-01 10:00:00
+3,cart,2018-08-02 12:00:00
+4,buy,2018-08-01 10:00:00
+4,open,2018-08-01 10:00:00
+4,open,2018-08-01 11:00:00
+4,cart,2018-08-01 11:06:00
+4,buy,2018-08-01 11:07:00
+`
+
+	reader, err := NewExportReader(ioutil.NopCloser(strings.NewReader(body)), EF_CSV)
+	if err != nil {
+		t.Fatalf("failed to create reader: %s", err)
+	}
+
+	funnel := Funnel{Steps: []string{"open", "cart", "buy"}, Window: 24 * time.Hour}
+	result, err := ComputeFunnel(reader, funnel)
+	if err != nil {
+		t.Fatalf("failed to compute funnel: %s", err)
+	}
+
+	expected := []FunnelStep{
+		{"open", 4, 1, 1, 0},
+		{"cart", 3, 0.75, 0.75, 4 * time.Minute},
+		{"buy", 2, 2. / 3, 0.5, 4*time.Minute + 30*time.Second},
+	}
+
+	for i, step := range result.Steps {
+		if step != expected[i] {
+			t.Errorf("wrong step %d: %+v != %+v", i, step, expected[i])
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package appmetrica
+
+import (
+	"context"
+)
+
+// defaultReportPageSize is a number of rows requested at once by
+// ReportIterator if query does not specify limit.
+const defaultReportPageSize = 10000
+
+// ReportIterator reads rows of table report page by page. Limit of query is
+// used as size of page and Offset as the first row; pages are requested
+// sequentially through rate limiter of Reporting API until total_rows rows
+// are read.
+type ReportIterator struct {
+	client *Client
+	ctx    context.Context
+	query  ReportQuery
+	report *Report
+	index  int
+	row    *ReportRow
+	err    error
+}
+
+// ReportRows возвращает итератор по всем строкам табличного отчёта, который
+// запрашивает страницы отчёта по мере чтения строк.
+func (c *Client) ReportRows(ctx context.Context, query *ReportQuery) (*ReportIterator, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	var iterator = &ReportIterator{client: c, ctx: ctx, query: *query}
+
+	if iterator.query.Limit == 0 {
+		iterator.query.Limit = defaultReportPageSize
+	}
+
+	if iterator.query.Offset == 0 {
+		iterator.query.Offset = 1
+	}
+
+	return iterator, nil
+}
+
+// Next advances iterator to the next row. It returns false when there are no
+// rows left or an error occured.
+func (it *ReportIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if it.report == nil || it.index == len(it.report.Data) {
+		if !it.fetch() {
+			return false
+		}
+	}
+
+	it.row = &it.report.Data[it.index]
+	it.index++
+	return true
+}
+
+// fetch requests the next page. It returns false if there are no pages left.
+func (it *ReportIterator) fetch() bool {
+	if it.report != nil {
+		// Offset is 1-based so that the last row has offset total_rows.
+		it.query.Offset += len(it.report.Data)
+
+		if len(it.report.Data) == 0 || it.query.Offset > it.report.TotalRows {
+			return false
+		}
+	}
+
+	var report, err = it.client.Report(it.ctx, &it.query)
+
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	it.report, it.index = report, 0
+	return len(report.Data) > 0
+}
+
+// Row returns current row. It is valid until the next call of Next.
+func (it *ReportIterator) Row() *ReportRow {
+	return it.row
+}
+
+// Err returns the first error occured during iteration.
+func (it *ReportIterator) Err() error {
+	return it.err
+}
+
+// Report returns the last requested page. Totals, Min, Max and TotalRows of
+// the page are computed over all rows of report.
+func (it *ReportIterator) Report() *Report {
+	return it.report
+}
@@ -0,0 +1,64 @@
+package appmetrica
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReportIterator(t *testing.T) {
+	const total = 25
+	var requests int
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		report := Report{TotalRows: total}
+		for i := offset; i < offset+limit && i <= total; i++ {
+			report.Data = append(report.Data, ReportRow{
+				Dimensions: []ReportDimension{{ID: strconv.Itoa(i)}},
+				Metrics:    []float64{float64(i)},
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/x-yametrika+json")
+		json.NewEncoder(w).Encode(&report)
+	}))
+	defer server.Close()
+
+	query := &ReportQuery{
+		IDs:        []int{84126},
+		Metrics:    Metrics(GE_Users),
+		Dimensions: Dimensions(GE_AppVersion),
+		Date1:      time.Now(),
+		Date2:      time.Now(),
+		Limit:      10,
+	}
+
+	rows, err := newTestClient(server).ReportRows(context.Background(), query)
+	if err != nil {
+		t.Fatalf("failed to create iterator: %s", err)
+	}
+
+	var count int
+	for rows.Next() {
+		count++
+		if id := rows.Row().Dimensions[0].ID; id != strconv.Itoa(count) {
+			t.Fatalf("wrong order of rows: %s at %d", id, count)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to iterate rows: %s", err)
+	}
+
+	if count != total || requests != 3 {
+		t.Errorf("wrong number of rows or requests: %d, %d", count, requests)
+	}
+}
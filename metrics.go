@@ -0,0 +1,287 @@
+package appmetrica
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Namespace is a prefix of Reporting API metrics and dimensions which
+// defines data source they are computed on. Metrics and dimensions of one
+// query should belong to the same namespace.
+type Namespace string
+
+const (
+	NS_General        Namespace = "ym:ge:"
+	NS_TrafficSources Namespace = "ym:ts:"
+	NS_ClientEvents   Namespace = "ym:ce:"
+	NS_Crashes        Namespace = "ym:cr2:"
+	NS_Errors         Namespace = "ym:er:"
+)
+
+// Metric is a name of Reporting API metric, e.g. `ym:ge:users`.
+type Metric string
+
+// Dimension is a name of Reporting API dimension, e.g. `ym:ge:date`.
+type Dimension string
+
+// Namespace returns namespace of metric.
+func (m Metric) Namespace() Namespace {
+	return namespaceOf(string(m))
+}
+
+// Namespace returns namespace of dimension.
+func (d Dimension) Namespace() Namespace {
+	return namespaceOf(string(d))
+}
+
+// General audience metrics and dimensions.
+const (
+	GE_Users                  Metric = "ym:ge:users"
+	GE_NewUsers               Metric = "ym:ge:newUsers"
+	GE_Sessions               Metric = "ym:ge:sessions"
+	GE_SessionsPerUser        Metric = "ym:ge:sessionsPerUser"
+	GE_AverageSessionDuration Metric = "ym:ge:averageSessionDuration"
+	GE_ActiveUsersPercentage  Metric = "ym:ge:activeUsersPercentage"
+
+	GE_Date                 Dimension = "ym:ge:date"
+	GE_OperatingSystem      Dimension = "ym:ge:operatingSystem"
+	GE_OSVersion            Dimension = "ym:ge:operatingSystemVersion"
+	GE_AppVersion           Dimension = "ym:ge:appVersion"
+	GE_BuildNumber          Dimension = "ym:ge:buildNumber"
+	GE_RegionCountry        Dimension = "ym:ge:regionCountry"
+	GE_RegionCity           Dimension = "ym:ge:regionCity"
+	GE_MobileDeviceBranding Dimension = "ym:ge:mobileDeviceBranding"
+	GE_MobileDeviceModel    Dimension = "ym:ge:mobileDeviceModel"
+	GE_DeviceType           Dimension = "ym:ge:deviceType"
+	GE_Locale               Dimension = "ym:ge:locale"
+)
+
+// Traffic sources (installations and clicks) metrics and dimensions.
+const (
+	TS_AdvInstallDevices Metric = "ym:ts:advInstallDevices"
+	TS_AdvClicks         Metric = "ym:ts:advClicks"
+	TS_AdvConversion     Metric = "ym:ts:advConversion"
+	TS_Reattributions    Metric = "ym:ts:reattributions"
+
+	TS_Date            Dimension = "ym:ts:date"
+	TS_Publisher       Dimension = "ym:ts:publisher"
+	TS_Tracker         Dimension = "ym:ts:tracker"
+	TS_Campaign        Dimension = "ym:ts:campaign"
+	TS_OperatingSystem Dimension = "ym:ts:operatingSystem"
+	TS_RegionCountry   Dimension = "ym:ts:regionCountry"
+)
+
+// Client events metrics and dimensions.
+const (
+	CE_AllEvents       Metric = "ym:ce:allEvents"
+	CE_Devices         Metric = "ym:ce:devices"
+	CE_EventsPerDevice Metric = "ym:ce:eventsPerDevice"
+
+	CE_Date            Dimension = "ym:ce:date"
+	CE_EventLabel      Dimension = "ym:ce:eventLabel"
+	CE_ParamsLevel1    Dimension = "ym:ce:paramsLevel1"
+	CE_ParamsLevel2    Dimension = "ym:ce:paramsLevel2"
+	CE_OperatingSystem Dimension = "ym:ce:operatingSystem"
+	CE_AppVersion      Dimension = "ym:ce:appVersion"
+)
+
+// Crashes metrics and dimensions.
+const (
+	CR_Crashes               Metric = "ym:cr2:crashes"
+	CR_CrashDevices          Metric = "ym:cr2:crashDevices"
+	CR_CrashesDevicesPercent Metric = "ym:cr2:crashesDevicesPercentage"
+
+	CR_Date              Dimension = "ym:cr2:date"
+	CR_CrashGroup        Dimension = "ym:cr2:crashGroup"
+	CR_CrashGroupName    Dimension = "ym:cr2:crashGroupName"
+	CR_OperatingSystem   Dimension = "ym:cr2:operatingSystem"
+	CR_AppVersion        Dimension = "ym:cr2:appVersion"
+	CR_MobileDeviceModel Dimension = "ym:cr2:mobileDeviceModel"
+)
+
+// Errors metrics and dimensions.
+const (
+	ERR_Errors       Metric = "ym:er:errors"
+	ERR_ErrorDevices Metric = "ym:er:errorDevices"
+
+	ERR_Date            Dimension = "ym:er:date"
+	ERR_ErrorID         Dimension = "ym:er:errorId"
+	ERR_ErrorMessage    Dimension = "ym:er:errorMessage"
+	ERR_OperatingSystem Dimension = "ym:er:operatingSystem"
+	ERR_AppVersion      Dimension = "ym:er:appVersion"
+)
+
+// reportMetrics and reportDimensions are catalogues of known metrics and
+// dimensions grouped by namespace.
+var (
+	reportMetrics    map[Namespace][]Metric
+	reportDimensions map[Namespace][]Dimension
+)
+
+func init() {
+	reportMetrics = map[Namespace][]Metric{
+		NS_General: {
+			GE_Users, GE_NewUsers, GE_Sessions, GE_SessionsPerUser,
+			GE_AverageSessionDuration, GE_ActiveUsersPercentage,
+		},
+		NS_TrafficSources: {
+			TS_AdvInstallDevices, TS_AdvClicks, TS_AdvConversion,
+			TS_Reattributions,
+		},
+		NS_ClientEvents: {CE_AllEvents, CE_Devices, CE_EventsPerDevice},
+		NS_Crashes: {
+			CR_Crashes, CR_CrashDevices, CR_CrashesDevicesPercent,
+		},
+		NS_Errors: {ERR_Errors, ERR_ErrorDevices},
+	}
+
+	reportDimensions = map[Namespace][]Dimension{
+		NS_General: {
+			GE_Date, GE_OperatingSystem, GE_OSVersion, GE_AppVersion,
+			GE_BuildNumber, GE_RegionCountry, GE_RegionCity,
+			GE_MobileDeviceBranding, GE_MobileDeviceModel, GE_DeviceType,
+			GE_Locale,
+		},
+		NS_TrafficSources: {
+			TS_Date, TS_Publisher, TS_Tracker, TS_Campaign,
+			TS_OperatingSystem, TS_RegionCountry,
+		},
+		NS_ClientEvents: {
+			CE_Date, CE_EventLabel, CE_ParamsLevel1, CE_ParamsLevel2,
+			CE_OperatingSystem, CE_AppVersion,
+		},
+		NS_Crashes: {
+			CR_Date, CR_CrashGroup, CR_CrashGroupName, CR_OperatingSystem,
+			CR_AppVersion, CR_MobileDeviceModel,
+		},
+		NS_Errors: {
+			ERR_Date, ERR_ErrorID, ERR_ErrorMessage, ERR_OperatingSystem,
+			ERR_AppVersion,
+		},
+	}
+}
+
+// ReportMetrics returns known metrics of namespace.
+func ReportMetrics(namespace Namespace) []Metric {
+	return append([]Metric(nil), reportMetrics[namespace]...)
+}
+
+// ReportDimensions returns known dimensions of namespace.
+func ReportDimensions(namespace Namespace) []Dimension {
+	return append([]Dimension(nil), reportDimensions[namespace]...)
+}
+
+// Metrics converts typed metrics to strings of ReportQuery.Metrics.
+func Metrics(metrics ...Metric) []string {
+	var names = make([]string, len(metrics))
+	for i, metric := range metrics {
+		names[i] = string(metric)
+	}
+	return names
+}
+
+// Dimensions converts typed dimensions to strings of ReportQuery.Dimensions.
+func Dimensions(dimensions ...Dimension) []string {
+	var names = make([]string, len(dimensions))
+	for i, dimension := range dimensions {
+		names[i] = string(dimension)
+	}
+	return names
+}
+
+// ValidateReportFields checks that metrics and dimensions are well-formed
+// and belong to the same namespace. Catalogue is not exhaustive so that
+// unknown names are rejected only if they are likely misspelled known ones;
+// error message contains the suggestion.
+func ValidateReportFields(metrics, dimensions []string) error {
+	var namespace Namespace
+	var first string
+
+	var check = func(name string, known []string) error {
+		var ns = namespaceOf(name)
+
+		if ns == "" || len(ns) == len(name) {
+			return errors.New(prefix + "malformed report field `" + name + "`")
+		}
+
+		if namespace == "" {
+			namespace, first = ns, name
+		} else if ns != namespace {
+			var msg = "report fields of different namespaces: `" + first +
+				"` and `" + name + "`"
+			return errors.New(prefix + msg)
+		}
+
+		if suggestion := suggestReportField(name, known); suggestion != "" {
+			var msg = "unknown report field `" + name + "`; did you mean `" +
+				suggestion + "`?"
+			return errors.New(prefix + msg)
+		}
+
+		return nil
+	}
+
+	for _, metric := range metrics {
+		if err := check(metric, knownMetrics(namespaceOf(metric))); err != nil {
+			return err
+		}
+	}
+
+	for _, dimension := range dimensions {
+		if err := check(dimension, knownDimensions(namespaceOf(dimension))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// namespaceOf returns part of name up to the last colon inclusive.
+func namespaceOf(name string) Namespace {
+	if colon := strings.LastIndexByte(name, ':'); colon != -1 {
+		return Namespace(name[:colon+1])
+	}
+	return ""
+}
+
+func knownMetrics(namespace Namespace) []string {
+	var names []string
+	for _, metric := range reportMetrics[namespace] {
+		names = append(names, string(metric))
+	}
+	return names
+}
+
+func knownDimensions(namespace Namespace) []string {
+	var names []string
+	for _, dimension := range reportDimensions[namespace] {
+		names = append(names, string(dimension))
+	}
+	return names
+}
+
+// suggestReportField returns known name which is close to unknown one. It
+// returns empty string if name is known or there is no close name.
+func suggestReportField(name string, known []string) string {
+	sort.Strings(known)
+
+	for _, candidate := range known {
+		if candidate == name {
+			return ""
+		}
+	}
+
+	var suggestion string
+	var best = 3 // Do not suggest names which differ too much.
+
+	for _, candidate := range known {
+		var distance = editDistance(strings.ToLower(name), strings.ToLower(candidate))
+
+		if distance < best {
+			suggestion, best = candidate, distance
+		}
+	}
+
+	return suggestion
+}
@@ -0,0 +1,799 @@
+package appmetrica
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+)
+
+// Constants of Parquet format which are used by ParquetSink.
+const (
+	parquetMagic = "PAR1"
+
+	// Physical types.
+	ptBoolean   = 0
+	ptInt32     = 1
+	ptInt64     = 2
+	ptFloat     = 4
+	ptDouble    = 5
+	ptByteArray = 6
+
+	// Converted (logical) types.
+	ctNone            = -1
+	ctUTF8            = 0
+	ctTimestampMillis = 9
+	ctUint8           = 11
+	ctUint16          = 12
+	ctUint32          = 13
+	ctUint64          = 14
+	ctInt8            = 15
+	ctInt16           = 16
+	ctJSON            = 19
+
+	// Repetition types.
+	rtRequired = 0
+	rtOptional = 1
+
+	// Encodings.
+	encPlain         = 0
+	encRLE           = 3
+	encRLEDictionary = 8
+
+	// Compression codecs.
+	codecUncompressed = 0
+	codecGzip         = 2
+
+	// Page types.
+	pageData       = 0
+	pageDictionary = 2
+)
+
+// DefaultParquetDictionary lists low-cardinality columns of export rows which
+// are dictionary encoded by default.
+var DefaultParquetDictionary = []string{
+	"app_package_name", "app_version_name", "city", "connection_type",
+	"country_iso_code", "device_locale", "device_manufacturer",
+	"device_model", "device_type", "event_name", "operator_name", "os_name",
+	"os_version", "publisher_name", "tracker_name",
+}
+
+// ParquetOptions configures ParquetSink. RowGroupSize is a number of rows in
+// row group (64k by default). Dictionary lists string columns which are
+// dictionary encoded (DefaultParquetDictionary if nil); dictionary encoding
+// is used for row group only if it makes column at least twice smaller in
+// number of values. Gzip enables compression of pages. Location is a time
+// zone of dates in rows passed to WriteRow (UTC if nil).
+type ParquetOptions struct {
+	RowGroupSize int
+	Dictionary   []string
+	Gzip         bool
+	Location     *time.Location
+}
+
+// ParquetSink writes typed export rows (e.g. ExportEvent) to Parquet file.
+// Schema is derived from row structure: every field with json tag becomes a
+// column; fields of embedded structures are flattened. Strings are stored
+// as UTF8 byte arrays, time.Time as optional millisecond timestamps and
+// json.RawMessage as optional JSON byte arrays.
+type ParquetSink struct {
+	writer  io.Writer
+	offset  int64
+	typ     reflect.Type
+	columns []*parquetColumn
+	options ParquetOptions
+	rows    int // rows in current row group
+	total   int64
+	groups  []parquetRowGroup
+	row     reflect.Value // scratch row for WriteRow
+	plans   map[string][][]int
+	err     error
+}
+
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	size   int64
+	rows   int
+}
+
+type parquetChunk struct {
+	column           *parquetColumn
+	encodings        []int32
+	values           int
+	uncompressedSize int64
+	compressedSize   int64
+	dataOffset       int64
+	dictionaryOffset int64 // -1 if there is no dictionary page
+}
+
+// NewParquetSink creates sink which writes rows of the same type as row
+// (structure or pointer to structure) to w. Use NewExportRow in order to get
+// row of export resource.
+func NewParquetSink(w io.Writer, row interface{}, options ParquetOptions) (*ParquetSink, error) {
+	var typ = reflect.TypeOf(row)
+
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, errors.New(prefix + "parquet row should be structure")
+	}
+
+	if options.RowGroupSize <= 0 {
+		options.RowGroupSize = 64 << 10
+	}
+
+	if options.Dictionary == nil {
+		options.Dictionary = DefaultParquetDictionary
+	}
+
+	if options.Location == nil {
+		options.Location = time.UTC
+	}
+
+	var sink = &ParquetSink{
+		writer:  w,
+		typ:     typ,
+		options: options,
+		row:     reflect.New(typ).Elem(),
+		plans:   make(map[string][][]int),
+	}
+
+	var dictionary = make(map[string]bool, len(options.Dictionary))
+
+	for _, name := range options.Dictionary {
+		dictionary[name] = true
+	}
+
+	var err = sink.collectColumns(typ, nil, make(map[string]bool), dictionary)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sink.write([]byte(parquetMagic))
+	return sink, sink.err
+}
+
+// collectColumns creates column for every tagged field in declaration order.
+func (s *ParquetSink) collectColumns(typ reflect.Type, path []int, seen, dictionary map[string]bool) error {
+	for i := 0; i < typ.NumField(); i++ {
+		var field = typ.Field(i)
+		var tag = field.Tag.Get("json")
+		var name = strings.Split(tag, ",")[0]
+		var index = append(path[:len(path):len(path)], i)
+
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			if err := s.collectColumns(field.Type, index, seen, dictionary); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if name == "" || name == "-" || seen[name] {
+			continue
+		}
+
+		var column = &parquetColumn{name: name, index: index, converted: ctNone}
+
+		if err := column.setType(field.Type); err != nil {
+			return err
+		}
+
+		column.dictionary = dictionary[name] && column.kind == ptByteArray
+		seen[name] = true
+		s.columns = append(s.columns, column)
+	}
+
+	return nil
+}
+
+// Write appends typed row to current row group. Row should have the same type
+// as prototype passed to NewParquetSink.
+func (s *ParquetSink) Write(row interface{}) error {
+	var value = reflect.ValueOf(row)
+
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+
+	if value.Type() != s.typ {
+		return errors.New(prefix + "unexpected type of parquet row: " + value.Type().String())
+	}
+
+	return s.append(value)
+}
+
+// WriteRow converts raw values of export row to typed row and appends it to
+// current row group. It makes ParquetSink usable as Sink.
+func (s *ParquetSink) WriteRow(fields, values []string) error {
+	var key = strings.Join(fields, ",")
+	var plan, ok = s.plans[key]
+
+	if !ok {
+		plan = planScan(s.typ, fields)
+		s.plans[key] = plan
+	}
+
+	s.row.Set(reflect.Zero(s.typ))
+
+	for i, index := range plan {
+		if index == nil {
+			continue
+		}
+
+		if err := setField(s.row.FieldByIndex(index), values[i], s.options.Location); err != nil {
+			return errors.New(prefix + "failed to convert field " + fields[i] + ": " + err.Error())
+		}
+	}
+
+	return s.append(s.row)
+}
+
+func (s *ParquetSink) append(row reflect.Value) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	for _, column := range s.columns {
+		column.append(row.FieldByIndex(column.index))
+	}
+
+	if s.rows++; s.rows >= s.options.RowGroupSize {
+		s.flush()
+	}
+
+	return s.err
+}
+
+// Close writes buffered row group and file footer. It does not close
+// underlying writer.
+func (s *ParquetSink) Close() error {
+	if s.rows > 0 {
+		s.flush()
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+
+	var footer = s.footer()
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+
+	s.write(footer)
+	s.write(length[:])
+	s.write([]byte(parquetMagic))
+	return s.err
+}
+
+func (s *ParquetSink) write(data []byte) {
+	if s.err != nil {
+		return
+	}
+
+	var written int
+	written, s.err = s.writer.Write(data)
+	s.offset += int64(written)
+}
+
+// flush writes column chunks of current row group.
+func (s *ParquetSink) flush() {
+	var group = parquetRowGroup{rows: s.rows}
+
+	for _, column := range s.columns {
+		var chunk = parquetChunk{
+			column:           column,
+			values:           column.count,
+			dictionaryOffset: -1,
+		}
+
+		var dictionary, indices, ok = column.buildDictionary()
+
+		if ok {
+			var body = appendPlainByteArrays(nil, dictionary)
+			chunk.dictionaryOffset = s.offset
+			s.writePage(&chunk, pageDictionary, len(dictionary), encPlain, body)
+
+			var width = bits.Len32(uint32(len(dictionary) - 1))
+
+			if width == 0 {
+				width = 1
+			}
+
+			body = column.appendLevels(nil)
+			body = append(body, byte(width))
+			body = appendRLE(body, indices, width)
+			chunk.dataOffset = s.offset
+			s.writePage(&chunk, pageData, column.count, encRLEDictionary, body)
+			chunk.encodings = []int32{encPlain, encRLE, encRLEDictionary}
+		} else {
+			var body = column.appendPlain(column.appendLevels(nil))
+			chunk.dataOffset = s.offset
+			s.writePage(&chunk, pageData, column.count, encPlain, body)
+			chunk.encodings = []int32{encPlain, encRLE}
+		}
+
+		group.size += chunk.uncompressedSize
+		group.chunks = append(group.chunks, chunk)
+		column.reset()
+	}
+
+	s.groups = append(s.groups, group)
+	s.total += int64(s.rows)
+	s.rows = 0
+}
+
+// writePage writes page header and (compressed) page body.
+func (s *ParquetSink) writePage(chunk *parquetChunk, kind, values, encoding int, body []byte) {
+	var compressed = body
+
+	if s.options.Gzip {
+		var buffer bytes.Buffer
+		var writer = gzip.NewWriter(&buffer)
+		writer.Write(body)
+		writer.Close()
+		compressed = buffer.Bytes()
+	}
+
+	var header = newThriftWriter()
+	header.writeI32(1, int32(kind))
+	header.writeI32(2, int32(len(body)))
+	header.writeI32(3, int32(len(compressed)))
+
+	if kind == pageDictionary {
+		header.beginStruct(7)
+		header.writeI32(1, int32(values))
+		header.writeI32(2, int32(encoding))
+		header.endStruct()
+	} else {
+		header.beginStruct(5)
+		header.writeI32(1, int32(values))
+		header.writeI32(2, int32(encoding))
+		header.writeI32(3, encRLE)
+		header.writeI32(4, encRLE)
+		header.endStruct()
+	}
+
+	var data = header.finish()
+
+	s.write(data)
+	s.write(compressed)
+	chunk.uncompressedSize += int64(len(data) + len(body))
+	chunk.compressedSize += int64(len(data) + len(compressed))
+}
+
+// footer encodes FileMetaData structure.
+func (s *ParquetSink) footer() []byte {
+	var w = newThriftWriter()
+	w.writeI32(1, 1) // version
+
+	w.beginList(2, thriftStruct, len(s.columns)+1)
+	w.beginElement()
+	w.writeBinary(4, []byte("schema"))
+	w.writeI32(5, int32(len(s.columns)))
+	w.endStruct()
+
+	for _, column := range s.columns {
+		var repetition int32 = rtRequired
+
+		if column.optional {
+			repetition = rtOptional
+		}
+
+		w.beginElement()
+		w.writeI32(1, column.kind)
+		w.writeI32(3, repetition)
+		w.writeBinary(4, []byte(column.name))
+
+		if column.converted != ctNone {
+			w.writeI32(6, column.converted)
+		}
+
+		w.endStruct()
+	}
+
+	w.writeI64(3, s.total)
+	w.beginList(4, thriftStruct, len(s.groups))
+
+	for _, group := range s.groups {
+		w.beginElement()
+		w.beginList(1, thriftStruct, len(group.chunks))
+
+		for _, chunk := range group.chunks {
+			var codec int32 = codecUncompressed
+
+			if s.options.Gzip {
+				codec = codecGzip
+			}
+
+			var first = chunk.dataOffset
+
+			if chunk.dictionaryOffset >= 0 {
+				first = chunk.dictionaryOffset
+			}
+
+			w.beginElement()
+			w.writeI64(2, first)
+			w.beginStruct(3)
+			w.writeI32(1, chunk.column.kind)
+			w.beginList(2, thriftI32, len(chunk.encodings))
+
+			for _, encoding := range chunk.encodings {
+				w.elementI32(encoding)
+			}
+
+			w.beginList(3, thriftBinary, 1)
+			w.elementBinary([]byte(chunk.column.name))
+			w.writeI32(4, codec)
+			w.writeI64(5, int64(chunk.values))
+			w.writeI64(6, chunk.uncompressedSize)
+			w.writeI64(7, chunk.compressedSize)
+			w.writeI64(9, chunk.dataOffset)
+
+			if chunk.dictionaryOffset >= 0 {
+				w.writeI64(11, chunk.dictionaryOffset)
+			}
+
+			w.endStruct()
+			w.endStruct()
+		}
+
+		w.writeI64(2, group.size)
+		w.writeI64(3, int64(group.rows))
+		w.endStruct()
+	}
+
+	w.writeBinary(6, []byte("appmetrica-go"))
+	return w.finish()
+}
+
+// parquetColumn buffers values of column for current row group.
+type parquetColumn struct {
+	name       string
+	index      []int
+	kind       int32
+	converted  int32
+	optional   bool
+	dictionary bool
+
+	count   int     // number of values including nulls
+	levels  []int32 // definition levels of optional column
+	bools   []bool
+	ints32  []int32
+	ints64  []int64
+	floats  []float32
+	doubles []float64
+	bytes   [][]byte
+}
+
+func (c *parquetColumn) setType(typ reflect.Type) error {
+	switch typ {
+	case timeType:
+		c.kind, c.converted, c.optional = ptInt64, ctTimestampMillis, true
+		return nil
+	case rawMessageType:
+		c.kind, c.converted, c.optional = ptByteArray, ctJSON, true
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		c.kind, c.converted = ptByteArray, ctUTF8
+	case reflect.Bool:
+		c.kind = ptBoolean
+	case reflect.Int8:
+		c.kind, c.converted = ptInt32, ctInt8
+	case reflect.Int16:
+		c.kind, c.converted = ptInt32, ctInt16
+	case reflect.Int32:
+		c.kind = ptInt32
+	case reflect.Int, reflect.Int64:
+		c.kind = ptInt64
+	case reflect.Uint8:
+		c.kind, c.converted = ptInt32, ctUint8
+	case reflect.Uint16:
+		c.kind, c.converted = ptInt32, ctUint16
+	case reflect.Uint32:
+		c.kind, c.converted = ptInt32, ctUint32
+	case reflect.Uint, reflect.Uint64:
+		c.kind, c.converted = ptInt64, ctUint64
+	case reflect.Float32:
+		c.kind = ptFloat
+	case reflect.Float64:
+		c.kind = ptDouble
+	default:
+		var msg = "unsupported type of parquet column " + c.name + ": " + typ.String()
+		return errors.New(prefix + msg)
+	}
+
+	return nil
+}
+
+func (c *parquetColumn) append(value reflect.Value) {
+	c.count++
+
+	switch value.Type() {
+	case timeType:
+		var date = value.Interface().(time.Time)
+		if c.define(!date.IsZero()) {
+			c.ints64 = append(c.ints64, date.UnixNano()/int64(time.Millisecond))
+		}
+		return
+	case rawMessageType:
+		var raw = value.Interface().(json.RawMessage)
+		if c.define(len(raw) > 0) {
+			c.bytes = append(c.bytes, append([]byte(nil), raw...))
+		}
+		return
+	}
+
+	switch c.kind {
+	case ptBoolean:
+		c.bools = append(c.bools, value.Bool())
+	case ptInt32:
+		if value.Kind() >= reflect.Uint && value.Kind() <= reflect.Uintptr {
+			c.ints32 = append(c.ints32, int32(value.Uint()))
+		} else {
+			c.ints32 = append(c.ints32, int32(value.Int()))
+		}
+	case ptInt64:
+		if value.Kind() >= reflect.Uint && value.Kind() <= reflect.Uintptr {
+			c.ints64 = append(c.ints64, int64(value.Uint()))
+		} else {
+			c.ints64 = append(c.ints64, value.Int())
+		}
+	case ptFloat:
+		c.floats = append(c.floats, float32(value.Float()))
+	case ptDouble:
+		c.doubles = append(c.doubles, value.Float())
+	case ptByteArray:
+		c.bytes = append(c.bytes, []byte(value.String()))
+	}
+}
+
+// define records definition level of optional value and reports whether
+// value is present.
+func (c *parquetColumn) define(present bool) bool {
+	if present {
+		c.levels = append(c.levels, 1)
+	} else {
+		c.levels = append(c.levels, 0)
+	}
+	return present
+}
+
+func (c *parquetColumn) reset() {
+	c.count = 0
+	c.levels = c.levels[:0]
+	c.bools = c.bools[:0]
+	c.ints32 = c.ints32[:0]
+	c.ints64 = c.ints64[:0]
+	c.floats = c.floats[:0]
+	c.doubles = c.doubles[:0]
+	c.bytes = c.bytes[:0]
+}
+
+// buildDictionary returns distinct values and indices of values in them if
+// dictionary encoding is enabled and profitable for column.
+func (c *parquetColumn) buildDictionary() ([][]byte, []int32, bool) {
+	if !c.dictionary || len(c.bytes) == 0 {
+		return nil, nil, false
+	}
+
+	var positions = make(map[string]int32)
+	var dictionary [][]byte
+	var indices = make([]int32, len(c.bytes))
+
+	for i, value := range c.bytes {
+		var position, ok = positions[string(value)]
+
+		if !ok {
+			position = int32(len(dictionary))
+			positions[string(value)] = position
+			dictionary = append(dictionary, value)
+
+			if 2*len(dictionary) > len(c.bytes) {
+				return nil, nil, false
+			}
+		}
+
+		indices[i] = position
+	}
+
+	return dictionary, indices, true
+}
+
+// appendLevels appends definition levels of optional column prefixed with
+// their length as data page v1 requires.
+func (c *parquetColumn) appendLevels(buffer []byte) []byte {
+	if !c.optional {
+		return buffer
+	}
+
+	var start = len(buffer)
+	buffer = append(buffer, 0, 0, 0, 0)
+	buffer = appendRLE(buffer, c.levels, 1)
+	binary.LittleEndian.PutUint32(buffer[start:], uint32(len(buffer)-start-4))
+	return buffer
+}
+
+// appendPlain appends non-null values in PLAIN encoding.
+func (c *parquetColumn) appendPlain(buffer []byte) []byte {
+	var scratch [8]byte
+
+	switch c.kind {
+	case ptBoolean:
+		var packed = make([]byte, (len(c.bools)+7)/8)
+		for i, flag := range c.bools {
+			if flag {
+				packed[i/8] |= 1 << uint(i%8)
+			}
+		}
+		buffer = append(buffer, packed...)
+	case ptInt32:
+		for _, value := range c.ints32 {
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(value))
+			buffer = append(buffer, scratch[:4]...)
+		}
+	case ptInt64:
+		for _, value := range c.ints64 {
+			binary.LittleEndian.PutUint64(scratch[:], uint64(value))
+			buffer = append(buffer, scratch[:]...)
+		}
+	case ptFloat:
+		for _, value := range c.floats {
+			binary.LittleEndian.PutUint32(scratch[:4], math.Float32bits(value))
+			buffer = append(buffer, scratch[:4]...)
+		}
+	case ptDouble:
+		for _, value := range c.doubles {
+			binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(value))
+			buffer = append(buffer, scratch[:]...)
+		}
+	case ptByteArray:
+		buffer = appendPlainByteArrays(buffer, c.bytes)
+	}
+
+	return buffer
+}
+
+func appendPlainByteArrays(buffer []byte, values [][]byte) []byte {
+	var length [4]byte
+
+	for _, value := range values {
+		binary.LittleEndian.PutUint32(length[:], uint32(len(value)))
+		buffer = append(buffer, length[:]...)
+		buffer = append(buffer, value...)
+	}
+
+	return buffer
+}
+
+// appendRLE appends values in RLE/bit-packing hybrid encoding. Only RLE runs
+// are emitted; this is valid for any sequence and efficient for sorted or
+// low-cardinality ones.
+func appendRLE(buffer []byte, values []int32, width int) []byte {
+	var size = (width + 7) / 8
+
+	for i := 0; i < len(values); {
+		var j = i + 1
+
+		for j < len(values) && values[j] == values[i] {
+			j++
+		}
+
+		buffer = appendUvarint(buffer, uint64(j-i)<<1)
+
+		for k := 0; k < size; k++ {
+			buffer = append(buffer, byte(uint32(values[i])>>uint(8*k)))
+		}
+
+		i = j
+	}
+
+	return buffer
+}
+
+func appendUvarint(buffer []byte, value uint64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	var length = binary.PutUvarint(scratch[:], value)
+	return append(buffer, scratch[:length]...)
+}
+
+// Types of Thrift compact protocol.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes Thrift structures in compact protocol which is used
+// for Parquet metadata.
+type thriftWriter struct {
+	buffer []byte
+	last   []int16 // identifier of the last field of every open structure
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{last: []int16{0}}
+}
+
+// finish closes top-level structure and returns encoded data.
+func (w *thriftWriter) finish() []byte {
+	return append(w.buffer, 0)
+}
+
+func (w *thriftWriter) field(id int16, kind byte) {
+	var last = &w.last[len(w.last)-1]
+
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buffer = append(w.buffer, byte(delta)<<4|kind)
+	} else {
+		w.buffer = append(w.buffer, kind)
+		w.buffer = appendUvarint(w.buffer, uint64(uint16(id<<1^id>>15)))
+	}
+
+	*last = id
+}
+
+func (w *thriftWriter) writeI32(id int16, value int32) {
+	w.field(id, thriftI32)
+	w.elementI32(value)
+}
+
+func (w *thriftWriter) writeI64(id int16, value int64) {
+	w.field(id, thriftI64)
+	w.buffer = appendUvarint(w.buffer, uint64(value<<1^value>>63))
+}
+
+func (w *thriftWriter) writeBinary(id int16, value []byte) {
+	w.field(id, thriftBinary)
+	w.elementBinary(value)
+}
+
+func (w *thriftWriter) beginStruct(id int16) {
+	w.field(id, thriftStruct)
+	w.last = append(w.last, 0)
+}
+
+func (w *thriftWriter) endStruct() {
+	w.buffer = append(w.buffer, 0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *thriftWriter) beginList(id int16, kind byte, size int) {
+	w.field(id, thriftList)
+
+	if size < 15 {
+		w.buffer = append(w.buffer, byte(size)<<4|kind)
+	} else {
+		w.buffer = append(w.buffer, 0xf0|kind)
+		w.buffer = appendUvarint(w.buffer, uint64(size))
+	}
+}
+
+// beginElement starts structure which is an element of list.
+func (w *thriftWriter) beginElement() {
+	w.last = append(w.last, 0)
+}
+
+func (w *thriftWriter) elementI32(value int32) {
+	w.buffer = appendUvarint(w.buffer, uint64(uint32(value<<1^value>>31)))
+}
+
+func (w *thriftWriter) elementBinary(value []byte) {
+	w.buffer = appendUvarint(w.buffer, uint64(len(value)))
+	w.buffer = append(w.buffer, value...)
+}
@@ -0,0 +1,176 @@
+package appmetrica
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// thriftReader decodes Thrift compact protocol into maps of field values.
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	value, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return value
+}
+
+func (r *thriftReader) value(kind byte) interface{} {
+	switch kind {
+	case 1, 2:
+		return kind == 1
+	case 5, 6:
+		value := r.uvarint()
+		return int64(value>>1) ^ -int64(value&1)
+	case 8:
+		length := int(r.uvarint())
+		r.pos += length
+		return r.data[r.pos-length : r.pos]
+	case 9:
+		header := r.data[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(header & 0x0f)
+		}
+		return list
+	case 12:
+		return r.structure()
+	}
+	panic("unexpected thrift type")
+}
+
+func (r *thriftReader) structure() map[int]interface{} {
+	fields := make(map[int]interface{})
+	last := 0
+
+	for {
+		header := r.data[r.pos]
+		r.pos++
+
+		if header == 0 {
+			return fields
+		}
+
+		if delta := int(header >> 4); delta != 0 {
+			last += delta
+		} else {
+			value := r.uvarint()
+			last = int(int64(value>>1) ^ -int64(value&1))
+		}
+
+		fields[last] = r.value(header & 0x0f)
+	}
+}
+
+func TestParquetSink(t *testing.T) {
+	var buffer bytes.Buffer
+
+	sink, err := NewParquetSink(&buffer, &ExportEvent{}, ParquetOptions{RowGroupSize: 3})
+	if err != nil {
+		t.Fatalf("failed to create sink: %s", err)
+	}
+
+	fields := []string{"os_name", "event_name", "event_datetime", "event_json"}
+	rows := [][]string{
+		{"android", "launch", "2018-08-01 10:00:00", `{"a":1}`},
+		{"android", "launch", "2018-08-01 11:00:00", ""},
+		{"ios", "launch", "", ""},
+		{"ios", "close", "2018-08-02 09:00:00", ""},
+	}
+
+	for _, row := range rows {
+		if err := sink.WriteRow(fields, row); err != nil {
+			t.Fatalf("failed to write row: %s", err)
+		}
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("failed to close sink: %s", err)
+	}
+
+	data := buffer.Bytes()
+
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatalf("wrong magic of parquet file")
+	}
+
+	length := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &thriftReader{data: data[len(data)-8-length : len(data)-8]}
+	metadata := footer.structure()
+
+	if metadata[3].(int64) != 4 {
+		t.Errorf("wrong number of rows: %v", metadata[3])
+	}
+
+	groups := metadata[4].([]interface{})
+	if len(groups) != 2 {
+		t.Fatalf("wrong number of row groups: %d", len(groups))
+	}
+
+	schema := metadata[2].([]interface{})
+	names := make(map[string]int)
+
+	for i, element := range schema[1:] {
+		names[string(element.(map[int]interface{})[4].([]byte))] = i
+	}
+
+	if len(names) != len(schema)-1 || names["appmetrica_device_id"] != 0 {
+		t.Errorf("wrong schema: %v", names)
+	}
+
+	if _, ok := names["os_name"]; !ok {
+		t.Fatalf("fields of embedded device are missing in schema")
+	}
+
+	// Column os_name of the first row group has two distinct values out of
+	// three so that it is not dictionary encoded while event_name is.
+	columns := groups[0].(map[int]interface{})[1].([]interface{})
+	osName := columns[names["os_name"]].(map[int]interface{})[3].(map[int]interface{})
+	eventName := columns[names["event_name"]].(map[int]interface{})[3].(map[int]interface{})
+
+	if _, ok := osName[11]; ok {
+		t.Errorf("column os_name should not have dictionary")
+	}
+
+	offset, ok := eventName[11].(int64)
+	if !ok {
+		t.Fatalf("column event_name should have dictionary")
+	}
+
+	page := &thriftReader{data: data, pos: int(offset)}
+	header := page.structure()
+
+	if header[1].(int64) != pageDictionary || header[7].(map[int]interface{})[1].(int64) != 1 {
+		t.Fatalf("wrong dictionary page header: %v", header)
+	}
+
+	if value := data[page.pos+4 : page.pos+10]; string(value) != "launch" {
+		t.Errorf("wrong dictionary value: %q", value)
+	}
+
+	// Timestamps are optional: the third row has no time.
+	datetime := columns[names["event_datetime"]].(map[int]interface{})[3].(map[int]interface{})
+	page = &thriftReader{data: data, pos: int(datetime[9].(int64))}
+	header = page.structure()
+	body := data[page.pos : page.pos+int(header[2].(int64))]
+	levels := int(binary.LittleEndian.Uint32(body))
+	values := body[4+levels:]
+
+	if len(values) != 16 {
+		t.Fatalf("wrong size of timestamps: %d", len(values))
+	}
+
+	at := time.Date(2018, 8, 1, 11, 0, 0, 0, time.UTC)
+	if millis := int64(binary.LittleEndian.Uint64(values[8:])); millis != at.UnixNano()/1e6 {
+		t.Errorf("wrong timestamp: %d", millis)
+	}
+}
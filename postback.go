@@ -0,0 +1,121 @@
+package appmetrica
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var allowedMacros map[string]struct{}
+
+func init() {
+	var macros = []string{
+		"android_id", "app_package_name", "app_version_name", "appmetrica_device_id",
+		"city", "click_datetime", "click_id", "click_ipv6", "click_timestamp",
+		"country_iso_code", "device_locale", "device_manufacturer",
+		"device_model", "device_type", "event_datetime", "event_name",
+		"event_timestamp", "google_aid", "install_datetime", "install_ipv6",
+		"install_timestamp", "ios_ifa", "ios_ifv", "os_name", "os_version",
+		"partner_event_name", "profile_id", "publisher_id", "revenue",
+		"revenue_currency", "tracker_name", "tracking_id", "transaction_id",
+		"windows_aid",
+	}
+
+	allowedMacros = make(map[string]struct{}, len(macros))
+
+	for _, macro := range macros {
+		allowedMacros[macro] = struct{}{}
+	}
+}
+
+// macroPattern matches macro of postback template like {click_id}.
+var macroPattern = regexp.MustCompile(`\{([a-z0-9_]+)\}`)
+
+// PostbackMacros returns macros which are used in postback URL or body
+// template. Every macro is returned once in order of the first occurrence.
+// Braces which do not enclose identifier (e.g. of JSON body) are not macros.
+func PostbackMacros(template string) []string {
+	var macros []string
+	var seen = make(map[string]struct{})
+
+	for _, match := range macroPattern.FindAllStringSubmatch(template, -1) {
+		if _, ok := seen[match[1]]; !ok {
+			seen[match[1]] = struct{}{}
+			macros = append(macros, match[1])
+		}
+	}
+
+	return macros
+}
+
+// ValidatePostback checks postback settings before they are sent to
+// AppMetrica. It verifies that URL is an absolute HTTP(S) URL, macros in URL
+// and body are known, and event postbacks have at least one event mapping.
+func ValidatePostback(postback *Postback) error {
+	switch postback.Type {
+	case PT_Install, PT_Reattribution:
+		if len(postback.Events) != 0 {
+			var msg = "events could be specified for event postback only"
+			return errors.New(prefix + msg)
+		}
+	case PT_Event:
+		if len(postback.Events) == 0 {
+			var msg = "event postback requires at least one event"
+			return errors.New(prefix + msg)
+		}
+		for _, event := range postback.Events {
+			if event.EventName == "" {
+				var msg = "event name of postback is empty"
+				return errors.New(prefix + msg)
+			}
+		}
+	default:
+		var msg = "unknown postback type: " + string(postback.Type)
+		return errors.New(prefix + msg)
+	}
+
+	switch strings.ToUpper(postback.Method) {
+	case "", "GET", "POST":
+	default:
+		var msg = "unsupported postback method: " + postback.Method
+		return errors.New(prefix + msg)
+	}
+
+	for _, template := range []string{postback.URL, postback.Body} {
+		for _, macro := range PostbackMacros(template) {
+			if _, ok := allowedMacros[macro]; !ok {
+				var msg = "unknown macro in postback template: {" + macro + "}"
+				return errors.New(prefix + msg)
+			}
+		}
+	}
+
+	// Macros are stripped before parsing since curly braces are not allowed
+	// in URL.
+	var link = stripMacros(postback.URL)
+
+	if strings.ContainsAny(link, "{}") {
+		var msg = "unexpected curly brace in postback url"
+		return errors.New(prefix + msg)
+	}
+
+	var parsed, err = url.Parse(link)
+
+	if err != nil {
+		return errors.New(prefix + "invalid postback url: " + err.Error())
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		var msg = "postback url should be absolute http(s) url"
+		return errors.New(prefix + msg)
+	}
+
+	return nil
+}
+
+// stripMacros replaces every macro in template with a placeholder which is
+// valid in any part of URL.
+func stripMacros(template string) string {
+	return macroPattern.ReplaceAllString(template, "macro")
+}
@@ -0,0 +1,105 @@
+package appmetrica
+
+import "testing"
+
+func TestPostback(t *testing.T) {
+	t.Run("Macros", func(t *testing.T) {
+		macros := PostbackMacros(`https://x.org/?a={ios_ifa}&b={click_id}&c={ios_ifa}`)
+
+		if len(macros) != 2 || macros[0] != "ios_ifa" || macros[1] != "click_id" {
+			t.Errorf("wrong macros: %v", macros)
+		}
+
+		macros = PostbackMacros(`{"click_id": "{click_id}", "data": {"event": "{event_name}"}}`)
+
+		if len(macros) != 2 || macros[0] != "click_id" || macros[1] != "event_name" {
+			t.Errorf("wrong macros of json body: %v", macros)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		postback := &Postback{
+			Type: PT_Event,
+			Events: []PostbackEvent{
+				{EventName: "purchase", PartnerEventName: "buy"},
+			},
+			URL: `https://partner.org/pb?id={click_id}&event={partner_event_name}`,
+		}
+
+		if err := ValidatePostback(postback); err != nil {
+			t.Errorf("valid postback was rejected: %s", err)
+		}
+
+		postback.URL = `https://partner.org/pb?id={click}`
+
+		if err := ValidatePostback(postback); err == nil {
+			t.Errorf("unknown macro was accepted")
+		}
+
+		postback.URL = `/pb?id={click_id}`
+
+		if err := ValidatePostback(postback); err == nil {
+			t.Errorf("relative url was accepted")
+		}
+
+		for _, link := range []string{`https://partner.org/pb?a={b`, `https://partner.org/pb?a=b}`, `https://partner.org/pb?a={B}`} {
+			postback.URL = link
+
+			if err := ValidatePostback(postback); err == nil {
+				t.Errorf("malformed url was accepted: %s", link)
+			}
+		}
+
+		postback.URL = `https://partner.org/pb`
+		postback.Method = "POST"
+		postback.Body = `{"click_id":"{click_id}","event":{"name":"{partner_event_name}"}}`
+
+		if err := ValidatePostback(postback); err != nil {
+			t.Errorf("json body was rejected: %s", err)
+		}
+
+		postback.Body = `{"click":"{click}"}`
+
+		if err := ValidatePostback(postback); err == nil {
+			t.Errorf("unknown macro in body was accepted")
+		}
+
+		postback.Body = ""
+		postback.Type = PT_Install
+
+		if err := ValidatePostback(postback); err == nil {
+			t.Errorf("install postback with events was accepted")
+		}
+	})
+}
+
+func TestModifyPostback(t *testing.T) {
+	var requests []managementRequest
+
+	server := newManagementServer(&requests, `{"postback": {"id": 7, "enabled": false}}`)
+	defer server.Close()
+
+	postback := &Postback{
+		ID:      7,
+		Type:    PT_Install,
+		URL:     `https://partner.org/pb?id={click_id}`,
+		Enabled: Bool(false),
+	}
+
+	result, err := newTestClient(server).ModifyPostback(84126, 42, postback)
+	if err != nil {
+		t.Fatalf("failed to modify postback: %s", err)
+	}
+
+	if result.Enabled == nil || *result.Enabled {
+		t.Errorf("postback should be disabled: %+v", result)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("expected one request instead of %d", len(requests))
+	}
+
+	if body := requests[0].Body.Postback; body == nil || body.Enabled == nil || *body.Enabled {
+		t.Errorf("disabled state is not sent: %+v", body)
+	}
+}
@@ -0,0 +1,74 @@
+package appmetrica
+
+import (
+	"errors"
+	"time"
+)
+
+// dateLayouts enumerates formats of dates which are returned by management
+// API.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseDate parses date returned by management API. Dates without time zone
+// are treated as UTC.
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, errors.New(prefix + "unknown date format: " + value)
+}
+
+// Expiry returns expiration time of APNs certificate. Token keys do not
+// expire so zero time is returned for them.
+func (c *APNsCredentials) Expiry() (time.Time, error) {
+	if c.ExpireDate == "" {
+		return time.Time{}, nil
+	}
+	return parseDate(c.ExpireDate)
+}
+
+// Expiry returns expiration time of FCM credentials or zero time if they do
+// not expire.
+func (c *FCMCredentials) Expiry() (time.Time, error) {
+	if c.ExpireDate == "" {
+		return time.Time{}, nil
+	}
+	return parseDate(c.ExpireDate)
+}
+
+// ExpiresWithin reports whether any of uploaded credentials expires in
+// duration d from now. It is useful for rotation jobs which renew
+// credentials in advance.
+func (p *PushCredentials) ExpiresWithin(d time.Duration) (bool, error) {
+	var deadline = time.Now().Add(d)
+	var expiries []func() (time.Time, error)
+
+	if p.APNs != nil {
+		expiries = append(expiries, p.APNs.Expiry)
+	}
+
+	if p.FCM != nil {
+		expiries = append(expiries, p.FCM.Expiry)
+	}
+
+	for _, expiry := range expiries {
+		var date, err = expiry()
+
+		if err != nil {
+			return false, err
+		}
+
+		if !date.IsZero() && date.Before(deadline) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,78 @@
+package appmetrica
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	var expected = time.Date(2018, 8, 1, 12, 30, 0, 0, time.UTC)
+
+	for _, value := range []string{
+		"2018-08-01T12:30:00Z",
+		"2018-08-01T15:30:00+03:00",
+		"2018-08-01T15:30:00+0300",
+		"2018-08-01 12:30:00",
+	} {
+		if date, err := parseDate(value); err != nil {
+			t.Errorf("failed to parse %q: %s", value, err)
+		} else if !date.Equal(expected) {
+			t.Errorf("wrong date of %q: %s", value, date)
+		}
+	}
+
+	if date, err := parseDate("2018-08-01"); err != nil || !date.Equal(expected.Truncate(24*time.Hour)) {
+		t.Errorf("wrong date: %s (%v)", date, err)
+	}
+
+	if _, err := parseDate("01.08.2018"); err == nil {
+		t.Errorf("unknown date format was accepted")
+	}
+}
+
+func TestPushCredentialsExpiry(t *testing.T) {
+	var soon = time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	var later = time.Now().Add(90 * 24 * time.Hour).UTC().Format(time.RFC3339)
+
+	apns := &APNsCredentials{KeyID: "key"}
+
+	if expiry, err := apns.Expiry(); err != nil || !expiry.IsZero() {
+		t.Errorf("token key should not expire: %s (%v)", expiry, err)
+	}
+
+	var tests = []struct {
+		Name        string
+		Credentials PushCredentials
+		Expires     bool
+	}{
+		{"Empty", PushCredentials{}, false},
+		{"TokenKey", PushCredentials{APNs: apns}, false},
+		{"Certificate", PushCredentials{APNs: &APNsCredentials{ExpireDate: soon}}, true},
+		{"FCM", PushCredentials{
+			APNs: &APNsCredentials{ExpireDate: later},
+			FCM:  &FCMCredentials{ExpireDate: soon},
+		}, true},
+		{"Later", PushCredentials{
+			APNs: &APNsCredentials{ExpireDate: later},
+			FCM:  &FCMCredentials{},
+		}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			expires, err := test.Credentials.ExpiresWithin(30 * 24 * time.Hour)
+			if err != nil {
+				t.Fatalf("failed to check expiry: %s", err)
+			}
+			if expires != test.Expires {
+				t.Errorf("expected %v instead of %v", test.Expires, expires)
+			}
+		})
+	}
+
+	invalid := PushCredentials{FCM: &FCMCredentials{ExpireDate: "tomorrow"}}
+
+	if _, err := invalid.ExpiresWithin(time.Hour); err == nil {
+		t.Errorf("invalid expire date was accepted")
+	}
+}
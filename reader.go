@@ -0,0 +1,421 @@
+package appmetrica
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rowSource produces rows of export one at a time. It returns io.EOF when
+// there are no rows left.
+type rowSource interface {
+	fields() []string
+	next() ([]string, error)
+	close() error
+}
+
+// ExportReader reads rows of Logs API export one by one. Rows could be read
+// as raw values, as map from field name to value, or scanned into typed
+// structures like ExportEvent. Fields of structures are matched to export
+// fields by json tags.
+type ExportReader struct {
+	source   rowSource
+	values   []string
+	err      error
+	plans    map[reflect.Type][][]int
+	location *time.Location
+}
+
+// NewExportReader creates reader of export body in the specified format.
+// Header of CSV body is read immediately.
+func NewExportReader(body io.ReadCloser, format ExportFormat) (*ExportReader, error) {
+	var source rowSource
+	var err error
+
+	switch format {
+	case "", EF_CSV:
+		source, err = newCSVSource(body)
+	case EF_JSON:
+		source, err = newJSONSource(body)
+	default:
+		err = errors.New(prefix + "unknown export format: " + string(format))
+	}
+
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+
+	return newExportReader(source), nil
+}
+
+func newExportReader(source rowSource) *ExportReader {
+	return &ExportReader{
+		source:   source,
+		plans:    make(map[reflect.Type][][]int),
+		location: time.UTC,
+	}
+}
+
+// ExportRows выгружает данные ресурса Logs API аналогично Export и
+// возвращает потоковый читатель строк выгрузки.
+func (c *Client) ExportRows(ctx context.Context, query *ExportQuery) (*ExportReader, error) {
+	var body, err = c.Export(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var reader *ExportReader
+
+	if reader, err = NewExportReader(body, query.format()); err == nil {
+		reader.SetLocation(query.location())
+	}
+
+	return reader, err
+}
+
+// SetLocation sets time zone which dates of rows are expressed in. Export
+// dates are in time zone of application; UTC is used by default.
+func (r *ExportReader) SetLocation(loc *time.Location) {
+	r.location = loc
+}
+
+// Next advances reader to the next row. It returns false when there are no
+// rows left or an error occured.
+func (r *ExportReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	r.values, r.err = r.source.next()
+	return r.err == nil
+}
+
+// Err returns the first error occured during reading except io.EOF.
+func (r *ExportReader) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
+
+// Close releases underlying response body.
+func (r *ExportReader) Close() error {
+	return r.source.close()
+}
+
+// Fields returns names of fields in order of values in row. For JSON export
+// fields are known only after the first call of Next.
+func (r *ExportReader) Fields() []string {
+	return r.source.fields()
+}
+
+// Values returns values of current row. The slice is valid until the next
+// call of Next.
+func (r *ExportReader) Values() []string {
+	return r.values
+}
+
+// Map returns current row as map from field name to value.
+func (r *ExportReader) Map() map[string]string {
+	var fields = r.source.fields()
+	var row = make(map[string]string, len(fields))
+
+	for i, field := range fields {
+		row[field] = r.values[i]
+	}
+
+	return row
+}
+
+// Scan stores values of current row in fields of structure pointed by row.
+// Fields which are absent in export are left untouched.
+func (r *ExportReader) Scan(row interface{}) error {
+	var value = reflect.ValueOf(row)
+
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return errors.New(prefix + "scan destination should be pointer to struct")
+	}
+
+	value = value.Elem()
+
+	var plan, ok = r.plans[value.Type()]
+
+	if !ok {
+		plan = planScan(value.Type(), r.source.fields())
+		r.plans[value.Type()] = plan
+	}
+
+	for i, index := range plan {
+		if index == nil {
+			continue
+		}
+
+		if err := setField(value.FieldByIndex(index), r.values[i], r.location); err != nil {
+			var field = r.source.fields()[i]
+			return errors.New(prefix + "failed to scan field " + field + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// planScan maps every export field to index path of struct field with the
+// same json tag or to nil if there is no such field. Fields of embedded
+// structures are promoted like encoding/json does.
+func planScan(typ reflect.Type, fields []string) [][]int {
+	var indices = make(map[string][]int, typ.NumField())
+	collectFields(typ, nil, indices)
+
+	var plan = make([][]int, len(fields))
+
+	for i, field := range fields {
+		plan[i] = indices[field]
+	}
+
+	return plan
+}
+
+func collectFields(typ reflect.Type, path []int, indices map[string][]int) {
+	for i := 0; i < typ.NumField(); i++ {
+		var field = typ.Field(i)
+		var tag = field.Tag.Get("json")
+		var name = strings.Split(tag, ",")[0]
+		var index = append(path[:len(path):len(path)], i)
+
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			collectFields(field.Type, index, indices)
+		} else if name != "" && name != "-" {
+			// Outer fields shadow promoted ones.
+			if prev, ok := indices[name]; !ok || len(prev) > len(index) {
+				indices[name] = index
+			}
+		}
+	}
+}
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// setField converts textual value of export field to type of struct field.
+// Empty values are converted to zero values; dates are parsed in location.
+func setField(field reflect.Value, value string, loc *time.Location) error {
+	switch field.Type() {
+	case timeType:
+		var date, err = parseExportTime(value, loc)
+		if err == nil {
+			field.Set(reflect.ValueOf(date))
+		}
+		return err
+	case rawMessageType:
+		var raw json.RawMessage
+		if value != "" && json.Valid([]byte(value)) {
+			raw = json.RawMessage(value)
+		} else if value != "" {
+			raw, _ = json.Marshal(value)
+		}
+		field.Set(reflect.ValueOf(raw))
+		return nil
+	}
+
+	if value == "" {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		var flag, err = strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(flag)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var number, err = strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(number)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var number, err = strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(number)
+	case reflect.Float32, reflect.Float64:
+		var number, err = strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(number)
+	default:
+		return errors.New("unsupported field type " + field.Type().String())
+	}
+
+	return nil
+}
+
+// parseExportTime parses either datetime in ExportDateFormat or date in
+// location, or unix timestamp in seconds.
+func parseExportTime(value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(timestamp, 0).In(loc), nil
+	}
+
+	if len(value) == len("2006-01-02") {
+		return time.ParseInLocation("2006-01-02", value, loc)
+	}
+
+	return time.ParseInLocation(ExportDateFormat, value, loc)
+}
+
+// csvSource reads rows of CSV export. The first line is a header.
+type csvSource struct {
+	body   io.ReadCloser
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVSource(body io.ReadCloser) (*csvSource, error) {
+	var source = &csvSource{body: body, reader: csv.NewReader(body)}
+	source.reader.ReuseRecord = true
+
+	var header, err = source.reader.Read()
+
+	if err == io.EOF {
+		return source, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	source.header = append([]string(nil), header...)
+	source.reader.FieldsPerRecord = len(header)
+	return source, nil
+}
+
+func (s *csvSource) fields() []string {
+	return s.header
+}
+
+func (s *csvSource) next() ([]string, error) {
+	return s.reader.Read()
+}
+
+func (s *csvSource) close() error {
+	return s.body.Close()
+}
+
+// jsonSource reads rows of JSON export which has form {"data": [{...}, ...]}
+// without decoding the whole body. Order of fields is taken from the first
+// row.
+type jsonSource struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+	header  []string
+	indices map[string]int
+	values  []string
+}
+
+func newJSONSource(body io.ReadCloser) (*jsonSource, error) {
+	var source = &jsonSource{body: body, decoder: json.NewDecoder(body)}
+
+	// Skip tokens up to the beginning of data array.
+	for _, expected := range []interface{}{json.Delim('{'), "data", json.Delim('[')} {
+		var token, err = source.decoder.Token()
+
+		if err != nil {
+			return nil, err
+		} else if token != expected {
+			return nil, errors.New(prefix + "unexpected structure of json export")
+		}
+	}
+
+	return source, nil
+}
+
+func (s *jsonSource) fields() []string {
+	return s.header
+}
+
+func (s *jsonSource) next() ([]string, error) {
+	if !s.decoder.More() {
+		return nil, io.EOF
+	}
+
+	if token, err := s.decoder.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('{') {
+		return nil, errors.New(prefix + "json export row is not an object")
+	}
+
+	var first = s.header == nil
+
+	if first {
+		s.indices = make(map[string]int)
+	} else {
+		for i := range s.values {
+			s.values[i] = ""
+		}
+	}
+
+	for s.decoder.More() {
+		var token, err = s.decoder.Token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		var key, _ = token.(string)
+		var raw json.RawMessage
+
+		if err = s.decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+
+		var value string
+
+		if len(raw) > 0 && raw[0] == '"' {
+			if err = json.Unmarshal(raw, &value); err != nil {
+				return nil, err
+			}
+		} else if string(raw) != "null" {
+			value = string(raw)
+		}
+
+		if index, ok := s.indices[key]; ok {
+			s.values[index] = value
+		} else if first {
+			s.indices[key] = len(s.header)
+			s.header = append(s.header, key)
+			s.values = append(s.values, value)
+		} else {
+			return nil, errors.New(prefix + "unexpected field in json export: " + key)
+		}
+	}
+
+	// Consume closing brace of row.
+	if _, err := s.decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	return s.values, nil
+}
+
+func (s *jsonSource) close() error {
+	return s.body.Close()
+}
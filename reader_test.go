@@ -0,0 +1,151 @@
+package appmetrica
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestExportReader(t *testing.T) {
+	t.Run("CSV", func(t *testing.T) {
+		body := ioutil.NopCloser(strings.NewReader("" +
+			"appmetrica_device_id,event_name,event_datetime,event_json\n" +
+			"998,launch,2018-08-01 12:30:00,\"{\"\"a\"\":1}\"\n" +
+			"999,close,2018-08-01 12:31:00,\n"))
+
+		reader, err := NewExportReader(body, EF_CSV)
+		if err != nil {
+			t.Fatalf("failed to create reader: %s", err)
+		}
+		defer reader.Close()
+
+		var events []ExportEvent
+
+		for reader.Next() {
+			var event ExportEvent
+			if err := reader.Scan(&event); err != nil {
+				t.Fatalf("failed to scan row: %s", err)
+			}
+			events = append(events, event)
+		}
+
+		if err := reader.Err(); err != nil {
+			t.Fatalf("failed to read rows: %s", err)
+		}
+
+		if len(events) != 2 {
+			t.Fatalf("wrong number of rows: %d", len(events))
+		}
+
+		if events[0].DeviceID != 998 || events[0].EventName != "launch" {
+			t.Errorf("wrong first row: %+v", events[0])
+		}
+
+		if string(events[0].EventJSON) != `{"a":1}` {
+			t.Errorf("wrong event json: %s", events[0].EventJSON)
+		}
+
+		if events[1].EventDatetime.Minute() != 31 || events[1].EventJSON != nil {
+			t.Errorf("wrong second row: %+v", events[1])
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		body := ioutil.NopCloser(strings.NewReader(`{"data":[` +
+			`{"event_name":"launch","mcc":"250","event_json":"{}"},` +
+			`{"mcc":"251","event_name":"close","event_json":null}]}`))
+
+		reader, err := NewExportReader(body, EF_JSON)
+		if err != nil {
+			t.Fatalf("failed to create reader: %s", err)
+		}
+		defer reader.Close()
+
+		var rows []map[string]string
+
+		for reader.Next() {
+			rows = append(rows, reader.Map())
+		}
+
+		if err := reader.Err(); err != nil {
+			t.Fatalf("failed to read rows: %s", err)
+		}
+
+		if fields := strings.Join(reader.Fields(), ","); fields != "event_name,mcc,event_json" {
+			t.Errorf("wrong fields: %s", fields)
+		}
+
+		if len(rows) != 2 || rows[1]["event_name"] != "close" || rows[1]["mcc"] != "251" {
+			t.Errorf("wrong rows: %v", rows)
+		}
+	})
+
+	t.Run("RowTypes", func(t *testing.T) {
+		for resource, typ := range exportRowTypes {
+			var fields []string
+			var indices = make(map[string][]int)
+			collectFields(typ, nil, indices)
+
+			for field := range indices {
+				fields = append(fields, field)
+			}
+
+			row := NewExportRow(resource)
+			source := &csvSource{header: fields}
+			reader := newExportReader(source)
+			reader.values = make([]string, len(fields))
+
+			if err := reader.Scan(row); err != nil {
+				t.Errorf("failed to scan empty row of %s: %s", resource, err)
+			}
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/csv")
+			fmt.Fprintln(w, "event_name")
+			for i := 0; i < 1000; i++ {
+				fmt.Fprintln(w, "event")
+				w.(http.Flusher).Flush() // Force chunked encoding.
+			}
+		}))
+		defer server.Close()
+
+		client := NewClient("token")
+		req, res := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(res)
+		req.SetRequestURI(server.URL + "/logs/v1/export/events.csv")
+
+		body, err := client.stream(context.Background(), req, res)
+		if err != nil {
+			t.Fatalf("failed to open stream: %s", err)
+		}
+
+		if res.StatusCode() != http.StatusOK || res.Header.ContentLength() != -1 {
+			t.Errorf("unexpected response header: %s", res.Header.String())
+		}
+
+		reader, err := NewExportReader(body, EF_CSV)
+		if err != nil {
+			t.Fatalf("failed to create reader: %s", err)
+		}
+		defer reader.Close()
+
+		var count int
+		for reader.Next() {
+			count++
+		}
+
+		if err := reader.Err(); err != nil || count != 1000 {
+			t.Errorf("wrong number of rows read: %d (%v)", count, err)
+		}
+	})
+}
@@ -0,0 +1,373 @@
+package appmetrica
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ReportDateFormat is a layout of date1 and date2 parameters of Reporting
+// API.
+const ReportDateFormat = "2006-01-02"
+
+// Limits of Reporting API on size of query.
+const (
+	maxReportMetrics    = 20
+	maxReportDimensions = 10
+	maxReportLimit      = 100000
+)
+
+// ReportAccuracy is a share of data which report is computed on. Besides
+// named levels API accepts fractions in (0, 1] (e.g. "0.1").
+type ReportAccuracy string
+
+const (
+	RA_Low    ReportAccuracy = "low"
+	RA_Medium ReportAccuracy = "medium"
+	RA_High   ReportAccuracy = "high"
+	RA_Full   ReportAccuracy = "full"
+)
+
+// validate checks that accuracy is either a named level or a fraction.
+func (a ReportAccuracy) validate() error {
+	switch a {
+	case "", RA_Low, RA_Medium, RA_High, RA_Full:
+		return nil
+	}
+
+	var share, err = strconv.ParseFloat(string(a), 64)
+
+	if err != nil || share <= 0 || share > 1 {
+		return errors.New(prefix + "invalid report accuracy: " + string(a))
+	}
+
+	return nil
+}
+
+// UnmarshalJSON decodes accuracy which API echoes either as a string or as a
+// number.
+func (a *ReportAccuracy) UnmarshalJSON(data []byte) error {
+	*a = ReportAccuracy(jsonScalar(data))
+	return nil
+}
+
+// ReportQuery describes table query of Reporting API. IDs are identifiers of
+// applications. Date1 and Date2 bound closed range of dates; only dates
+// matter and they are taken in their own time zone. Filters is an expression
+// in Reporting API filter syntax (see Filter for builder). Sort lists metrics
+// or dimensions; prefix `-` means descending order. Offset is 1-based as in
+// API; zero values of Limit, Offset and Accuracy mean defaults of API.
+type ReportQuery struct {
+	IDs        []int
+	Metrics    []string
+	Dimensions []string
+	Date1      time.Time
+	Date2      time.Time
+	Filters    string
+	Sort       []string
+	Limit      int
+	Offset     int
+	Accuracy   ReportAccuracy
+}
+
+// Validate checks that query has all required parameters and fits limits of
+// API.
+func (q *ReportQuery) Validate() error {
+	switch {
+	case len(q.IDs) == 0:
+		return errors.New(prefix + "application ids are not specified")
+	case len(q.Metrics) == 0:
+		return errors.New(prefix + "report metrics are not specified")
+	case len(q.Metrics) > maxReportMetrics:
+		return errors.New(prefix + "too many report metrics")
+	case len(q.Dimensions) > maxReportDimensions:
+		return errors.New(prefix + "too many report dimensions")
+	case q.Date1.IsZero() || q.Date2.IsZero():
+		return errors.New(prefix + "report date range is not specified")
+	case q.Date2.Format(ReportDateFormat) < q.Date1.Format(ReportDateFormat):
+		return errors.New(prefix + "report date range is empty")
+	case q.Limit < 0 || q.Limit > maxReportLimit:
+		return errors.New(prefix + "report limit is out of range")
+	case q.Offset < 0:
+		return errors.New(prefix + "report offset is negative")
+	}
+
+	if err := q.Accuracy.validate(); err != nil {
+		return err
+	}
+
+	for _, field := range q.Sort {
+		if !q.selects(strings.TrimPrefix(field, "-")) {
+			return errors.New(prefix + "report is sorted by unselected field: " + field)
+		}
+	}
+
+	return ValidateReportFields(q.Metrics, q.Dimensions)
+}
+
+// selects reports whether field is one of metrics or dimensions of query.
+func (q *ReportQuery) selects(field string) bool {
+	for _, name := range q.Metrics {
+		if name == field {
+			return true
+		}
+	}
+
+	for _, name := range q.Dimensions {
+		if name == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q *ReportQuery) encode(req *fasthttp.Request, path string) {
+	var ids = make([]string, len(q.IDs))
+
+	for i, id := range q.IDs {
+		ids[i] = strconv.Itoa(id)
+	}
+
+	uri := req.URI()
+	uri.SetPath(path)
+
+	args := uri.QueryArgs()
+	args.Set(`ids`, strings.Join(ids, ","))
+	args.Set(`metrics`, strings.Join(q.Metrics, ","))
+	args.Set(`date1`, q.Date1.Format(ReportDateFormat))
+	args.Set(`date2`, q.Date2.Format(ReportDateFormat))
+
+	if len(q.Dimensions) > 0 {
+		args.Set(`dimensions`, strings.Join(q.Dimensions, ","))
+	}
+
+	if q.Filters != "" {
+		args.Set(`filters`, q.Filters)
+	}
+
+	if len(q.Sort) > 0 {
+		args.Set(`sort`, strings.Join(q.Sort, ","))
+	}
+
+	if q.Limit > 0 {
+		args.Set(`limit`, strconv.Itoa(q.Limit))
+	}
+
+	if q.Offset > 0 {
+		args.Set(`offset`, strconv.Itoa(q.Offset))
+	}
+
+	if q.Accuracy != "" {
+		args.Set(`accuracy`, string(q.Accuracy))
+	}
+}
+
+// Report выполняет табличный запрос к Reporting API (stat/v1/data) и
+// возвращает строки отчёта вместе с итогами, минимумами и максимумами
+// метрик.
+func (c *Client) Report(ctx context.Context, query *ReportQuery) (*Report, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	req, res := c.prepare()
+	query.encode(req, `/stat/v1/data`)
+
+	var report Report
+
+	if err := c.report(ctx, req, res, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// report makes request to Reporting API and decodes successful response into
+// obj. Unlike Management API responses of Reporting API are not wrapped into
+// envelope so that they are not decoded with Response. Responses are taken
+// from and put to report cache if it is enabled. Decoded reports are checked
+// against sampling policy.
+func (c *Client) report(ctx context.Context, req *fasthttp.Request, res *fasthttp.Response, obj interface{}) error {
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(res)
+
+	var key string
+
+	c.applyAccuracy(req)
+
+	if c.cache != nil {
+		key = c.cacheKey(req)
+
+		if body, ok := c.cache.Get(key); ok {
+			if err := json.Unmarshal(body, obj); err != nil {
+				return err
+			}
+			return c.checkSampling(req, obj)
+		}
+	}
+
+	if err := c.wait(ctx, reportingAPI); err != nil {
+		return err
+	}
+
+	if err := c.doContext(ctx, req, res); err != nil {
+		return err
+	}
+
+	if res.StatusCode() != http.StatusOK {
+		return c.processError(res)
+	}
+
+	if err := json.Unmarshal(res.Body(), obj); err != nil {
+		return err
+	}
+
+	if c.cache != nil {
+		c.cache.Set(key, append([]byte(nil), res.Body()...), c.cacheTTL(req))
+	}
+
+	return c.checkSampling(req, obj)
+}
+
+// doContext performs request until response is received or context is done.
+// Request is performed on copies of req and res in background so that they
+// are not used after return on cancellation; copies are left to garbage
+// collector in this case. Deadline of context is passed to HTTP client in
+// order to close connection of request which is timed out.
+func (c *Client) doContext(ctx context.Context, req *fasthttp.Request, res *fasthttp.Response) error {
+	var reqCopy = fasthttp.AcquireRequest()
+	var resCopy = fasthttp.AcquireResponse()
+	var deadline, bounded = ctx.Deadline()
+	var done = make(chan error, 1)
+
+	req.CopyTo(reqCopy)
+
+	go func() {
+		if bounded {
+			done <- c.client.DoDeadline(reqCopy, resCopy, deadline)
+		} else {
+			done <- c.client.Do(reqCopy, resCopy)
+		}
+	}()
+
+	select {
+	case err := <-done:
+		resCopy.CopyTo(res)
+		fasthttp.ReleaseRequest(reqCopy)
+		fasthttp.ReleaseResponse(resCopy)
+
+		if bounded && err == fasthttp.ErrTimeout {
+			// Client could reach deadline a bit earlier than context.
+			return context.DeadlineExceeded
+		} else if err != nil {
+			return err
+		}
+
+		return ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DrilldownQuery describes tree query of Reporting API. Rows are values of
+// dimension which follows the last of ParentID in Dimensions; empty ParentID
+// means the top level of tree.
+type DrilldownQuery struct {
+	ReportQuery
+	ParentID []string
+}
+
+// Validate checks parameters of query.
+func (q *DrilldownQuery) Validate() error {
+	if len(q.Dimensions) == 0 {
+		return errors.New(prefix + "drilldown dimensions are not specified")
+	}
+	if len(q.ParentID) >= len(q.Dimensions) {
+		return errors.New(prefix + "drilldown parent is deeper than dimensions")
+	}
+	return q.ReportQuery.Validate()
+}
+
+// ReportDrilldown выполняет запрос к Reporting API в виде дерева
+// (stat/v1/data/drilldown) и возвращает дочерние узлы указанного
+// родительского узла.
+func (c *Client) ReportDrilldown(ctx context.Context, query *DrilldownQuery) (*DrilldownReport, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	req, res := c.prepare()
+	query.encode(req, `/stat/v1/data/drilldown`)
+
+	if len(query.ParentID) > 0 {
+		var parent, _ = json.Marshal(query.ParentID)
+		req.URI().QueryArgs().SetBytesV(`parent_id`, parent)
+	}
+
+	var report DrilldownReport
+
+	if err := c.report(ctx, req, res, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// ComparisonSegment is a segment of comparison report. Zero dates mean date
+// range of query.
+type ComparisonSegment struct {
+	Filters string
+	Date1   time.Time
+	Date2   time.Time
+}
+
+// ComparisonQuery describes query which compares metrics of two segments.
+// Filters of query apply to both segments.
+type ComparisonQuery struct {
+	ReportQuery
+	A ComparisonSegment
+	B ComparisonSegment
+}
+
+// ReportComparison выполняет запрос сравнения сегментов A и B к Reporting API
+// (stat/v1/data/comparison).
+func (c *Client) ReportComparison(ctx context.Context, query *ComparisonQuery) (*ComparisonReport, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	req, res := c.prepare()
+	query.encode(req, `/stat/v1/data/comparison`)
+
+	var args = req.URI().QueryArgs()
+	var segments = map[string]*ComparisonSegment{`a`: &query.A, `b`: &query.B}
+
+	for name, segment := range segments {
+		if segment.Filters != "" {
+			args.Set(`filters_`+name, segment.Filters)
+		}
+
+		if !segment.Date1.IsZero() {
+			args.Set(`date1_`+name, segment.Date1.Format(ReportDateFormat))
+		}
+
+		if !segment.Date2.IsZero() {
+			args.Set(`date2_`+name, segment.Date2.Format(ReportDateFormat))
+		}
+	}
+
+	var report ComparisonReport
+
+	if err := c.report(ctx, req, res, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
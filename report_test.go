@@ -0,0 +1,440 @@
+package appmetrica
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const reportBody = `{
+	"query": {
+		"ids": [84126], "dimensions": ["ym:ge:operatingSystem"],
+		"metrics": ["ym:ge:users"], "sort": ["-ym:ge:users"],
+		"date1": "2018-08-01", "date2": "2018-08-07", "limit": 2, "offset": 1
+	},
+	"data": [
+		{"dimensions": [{"id": "android", "name": "Android"}], "metrics": [120.0]},
+		{"dimensions": [{"id": 2, "name": null}], "metrics": [80.0]}
+	],
+	"total_rows": 3,
+	"totals": [210.0], "min": [10.0], "max": [120.0]
+}`
+
+func TestReport(t *testing.T) {
+	query := &ReportQuery{
+		IDs:        []int{84126},
+		Metrics:    []string{"ym:ge:users"},
+		Dimensions: []string{"ym:ge:operatingSystem"},
+		Date1:      time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+		Date2:      time.Date(2018, 8, 7, 0, 0, 0, 0, time.UTC),
+		Sort:       []string{"-ym:ge:users"},
+		Limit:      2,
+		Accuracy:   RA_Full,
+	}
+
+	t.Run("Validate", func(t *testing.T) {
+		if err := query.Validate(); err != nil {
+			t.Errorf("valid query was rejected: %s", err)
+		}
+
+		invalid := *query
+		invalid.Accuracy = "1.5"
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("accuracy out of range was accepted")
+		}
+
+		invalid = *query
+		invalid.Date2 = invalid.Date1.Add(-time.Hour)
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("empty date range was accepted")
+		}
+	})
+
+	t.Run("Request", func(t *testing.T) {
+		var uri string
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uri = r.URL.RawQuery
+			w.Header().Set("Content-Type", "application/x-yametrika+json")
+			if r.URL.Query().Get("ids") == "0" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"errors": [{"error_type": "invalid_parameter", "message": "wrong ids"}], "code": 400, "message": "wrong ids"}`)
+				return
+			}
+			fmt.Fprint(w, reportBody)
+		}))
+		defer server.Close()
+
+		client := NewClient("token")
+		req, res := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+		query.encode(req, "/stat/v1/data")
+		req.URI().SetScheme("http")
+		req.URI().SetHost(strings.TrimPrefix(server.URL, "http://"))
+
+		var report Report
+		if err := client.report(context.Background(), req, res, &report); err != nil {
+			t.Fatalf("failed to make request: %s", err)
+		}
+
+		for _, arg := range []string{"ids=84126", "date2=2018-08-07", "limit=2", "accuracy=full"} {
+			if !strings.Contains(uri, arg) {
+				t.Errorf("argument %s is missing in %s", arg, uri)
+			}
+		}
+
+		if report.TotalRows != 3 || len(report.Data) != 2 || report.Totals[0] != 210 {
+			t.Errorf("wrong report: %+v", report)
+		}
+
+		if dim := report.Data[1].Dimensions[0]; dim.ID != "2" || dim.Name != "" {
+			t.Errorf("wrong dimension: %+v", dim)
+		}
+
+		if report.Query.Sort[0] != "-ym:ge:users" || report.Query.Offset != 1 {
+			t.Errorf("wrong query echo: %+v", report.Query)
+		}
+
+		req, res = fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+		req.SetRequestURI(server.URL + "/stat/v1/data?ids=0")
+
+		err := client.report(context.Background(), req, res, &report)
+		if err == nil || !strings.Contains(err.Error(), "wrong ids") {
+			t.Errorf("error of API was not reported: %v", err)
+		}
+	})
+}
+
+func TestReportFields(t *testing.T) {
+	metrics := Metrics(GE_Users, GE_NewUsers)
+	dimensions := Dimensions(GE_Date, GE_OperatingSystem)
+
+	if err := ValidateReportFields(metrics, dimensions); err != nil {
+		t.Errorf("valid fields were rejected: %s", err)
+	}
+
+	err := ValidateReportFields(metrics, Dimensions(CE_EventLabel))
+	if err == nil || !strings.Contains(err.Error(), "different namespaces") {
+		t.Errorf("fields of different namespaces were accepted: %v", err)
+	}
+
+	err = ValidateReportFields([]string{"ym:ge:usres"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "`ym:ge:users`") {
+		t.Errorf("wrong suggestion for misspelled metric: %v", err)
+	}
+
+	if err := ValidateReportFields([]string{"ym:ge:crashFreeUsersPercentage"}, nil); err != nil {
+		t.Errorf("metric missing in catalogue was rejected: %s", err)
+	}
+
+	if ns := CR_Crashes.Namespace(); ns != NS_Crashes {
+		t.Errorf("wrong namespace of metric: %s", ns)
+	}
+}
+
+func TestReportByTime(t *testing.T) {
+	body := `{
+		"query": {"ids": [84126], "metrics": ["ym:ge:users"], "date1": "2018-08-01", "date2": "2018-08-02"},
+		"data": [{"dimensions": [{"id": "ios", "name": "iOS"}], "metrics": [[10, 12]]}],
+		"total_rows": 1,
+		"totals": [[10, 12]],
+		"time_intervals": [["2018-08-01", "2018-08-01"], ["2018-08-02", "2018-08-02"]]
+	}`
+
+	loc := time.FixedZone("MSK", 3*3600)
+
+	var report ByTimeReport
+	if err := json.Unmarshal([]byte(body), &report); err != nil {
+		t.Fatalf("failed to decode report: %s", err)
+	}
+
+	if err := report.align(loc); err != nil {
+		t.Fatalf("failed to align report: %s", err)
+	}
+
+	if len(report.Buckets) != 2 || !report.Buckets[1].Equal(time.Date(2018, 8, 1, 21, 0, 0, 0, time.UTC)) {
+		t.Errorf("wrong buckets: %v", report.Buckets)
+	}
+
+	if series := report.Data[0].Metrics[0]; series[1] != 12 {
+		t.Errorf("wrong series: %v", series)
+	}
+
+	report.Totals = [][]float64{{10}}
+	if err := report.align(loc); err == nil {
+		t.Errorf("misaligned totals were accepted")
+	}
+
+	query := &ByTimeQuery{ReportQuery: ReportQuery{
+		IDs:     []int{84126},
+		Metrics: Metrics(GE_Users),
+		Date1:   time.Now(),
+		Date2:   time.Now(),
+	}}
+
+	if err := query.Validate(); err == nil {
+		t.Errorf("query without group was accepted")
+	}
+
+	query.Group = RG_Week
+	if err := query.Validate(); err != nil {
+		t.Errorf("valid query was rejected: %s", err)
+	}
+}
+
+func TestReportDrilldown(t *testing.T) {
+	body := `{
+		"query": {"ids": [84126], "dimensions": ["ym:ge:operatingSystem", "ym:ge:appVersion"]},
+		"data": [{"dimension": {"id": "1.0", "name": "1.0"}, "metrics": [5], "expand": false}],
+		"total_rows": 1, "totals": [5], "min": [5], "max": [5]
+	}`
+
+	var report DrilldownReport
+	if err := json.Unmarshal([]byte(body), &report); err != nil {
+		t.Fatalf("failed to decode report: %s", err)
+	}
+
+	if row := report.Data[0]; row.Dimension.ID != "1.0" || row.Expand || row.Metrics[0] != 5 {
+		t.Errorf("wrong row: %+v", row)
+	}
+
+	query := &DrilldownQuery{
+		ReportQuery: ReportQuery{
+			IDs:        []int{84126},
+			Metrics:    Metrics(GE_Users),
+			Dimensions: Dimensions(GE_OperatingSystem),
+			Date1:      time.Now(),
+			Date2:      time.Now(),
+		},
+		ParentID: []string{"android"},
+	}
+
+	if err := query.Validate(); err == nil {
+		t.Errorf("parent of the last dimension was accepted")
+	}
+
+	query.Dimensions, query.ParentID = nil, nil
+	if err := query.Validate(); err == nil || !strings.Contains(err.Error(), "dimensions are not specified") {
+		t.Errorf("wrong error of query without dimensions: %v", err)
+	}
+}
+
+func TestReportDrilldownParent(t *testing.T) {
+	var parent string
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parent = r.URL.Query().Get("parent_id")
+		w.Header().Set("Content-Type", "application/x-yametrika+json")
+		w.Write([]byte(`{"data": [], "total_rows": 0, "totals": [0]}`))
+	}))
+	defer server.Close()
+
+	query := &DrilldownQuery{
+		ReportQuery: ReportQuery{
+			IDs:        []int{84126},
+			Metrics:    Metrics(GE_Users),
+			Dimensions: Dimensions(GE_OperatingSystem, GE_AppVersion, GE_Date),
+			Date1:      time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+			Date2:      time.Date(2018, 8, 7, 0, 0, 0, 0, time.UTC),
+		},
+		ParentID: []string{"android", "1.0 \"beta\""},
+	}
+
+	if _, err := newTestClient(server).ReportDrilldown(context.Background(), query); err != nil {
+		t.Fatalf("failed to request report: %s", err)
+	}
+
+	var ids []string
+	if err := json.Unmarshal([]byte(parent), &ids); err != nil {
+		t.Fatalf("parent is not json array: %q", parent)
+	}
+
+	if len(ids) != 2 || ids[0] != "android" || ids[1] != "1.0 \"beta\"" {
+		t.Errorf("wrong parent: %q", parent)
+	}
+}
+
+func TestReportComparison(t *testing.T) {
+	body := `{
+		"data": [{"dimensions": [{"name": "iOS"}], "metrics": {"a": [10], "b": [7]}}],
+		"total_rows": 1, "totals": {"a": [10], "b": [7]}
+	}`
+
+	var report ComparisonReport
+	if err := json.Unmarshal([]byte(body), &report); err != nil {
+		t.Fatalf("failed to decode report: %s", err)
+	}
+
+	if row := report.Data[0]; row.Metrics.A[0] != 10 || row.Metrics.B[0] != 7 || report.Totals.B[0] != 7 {
+		t.Errorf("wrong report: %+v", report)
+	}
+}
+
+func TestReportDeadline(t *testing.T) {
+	var release = make(chan struct{})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/x-yametrika+json")
+		w.Write([]byte(reportBody))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	query := &ReportQuery{
+		IDs:     []int{84126},
+		Metrics: Metrics(GE_Users),
+		Date1:   time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+		Date2:   time.Date(2018, 8, 7, 0, 0, 0, 0, time.UTC),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var start = time.Now()
+
+	if _, err := newTestClient(server).Report(ctx, query); err != context.DeadlineExceeded {
+		t.Errorf("report should be stopped by context: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("deadline of context is ignored: %s", elapsed)
+	}
+}
+
+func TestReportCancel(t *testing.T) {
+	var release = make(chan struct{})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/x-yametrika+json")
+		w.Write([]byte(reportBody))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	query := &ReportQuery{
+		IDs:     []int{84126},
+		Metrics: Metrics(GE_Users),
+		Date1:   time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+		Date2:   time.Date(2018, 8, 7, 0, 0, 0, 0, time.UTC),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	var start = time.Now()
+
+	if _, err := newTestClient(server).Report(ctx, query); err != context.Canceled {
+		t.Errorf("report should be stopped by context: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancellation of context is ignored: %s", elapsed)
+	}
+}
+
+func TestReportByTimeDates(t *testing.T) {
+	var date1, date2 string
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		date1, date2 = r.URL.Query().Get("date1"), r.URL.Query().Get("date2")
+		w.Header().Set("Content-Type", "application/x-yametrika+json")
+		w.Write([]byte(`{
+			"data": [], "total_rows": 0, "totals": [[1]],
+			"time_intervals": [["2018-08-01 00:00:00", "2018-08-07 23:59:59"]]
+		}`))
+	}))
+	defer server.Close()
+
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skipf("time zone database is not available: %s", err)
+	}
+
+	query := &ByTimeQuery{
+		ReportQuery: ReportQuery{
+			IDs:     []int{84126},
+			Metrics: Metrics(GE_Users),
+			Date1:   time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+			Date2:   time.Date(2018, 8, 7, 0, 0, 0, 0, time.UTC),
+		},
+		Group:    RG_Week,
+		Location: loc,
+	}
+
+	report, err := newTestClient(server).ReportByTime(context.Background(), query)
+	if err != nil {
+		t.Fatalf("failed to request report: %s", err)
+	}
+
+	if date1 != "2018-08-01" || date2 != "2018-08-07" {
+		t.Errorf("dates of range are shifted: %s..%s", date1, date2)
+	}
+
+	if bucket := report.Buckets[0]; !bucket.Equal(time.Date(2018, 8, 1, 0, 0, 0, 0, loc)) {
+		t.Errorf("time interval is not parsed in location: %s", bucket)
+	}
+}
+
+func TestReportComparisonSegments(t *testing.T) {
+	var args = make(map[string]string)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name := range r.URL.Query() {
+			args[name] = r.URL.Query().Get(name)
+		}
+		w.Header().Set("Content-Type", "application/x-yametrika+json")
+		w.Write([]byte(`{"data": [], "total_rows": 0, "totals": {"a": [0], "b": [0]}}`))
+	}))
+	defer server.Close()
+
+	query := &ComparisonQuery{
+		ReportQuery: ReportQuery{
+			IDs:     []int{84126},
+			Metrics: Metrics(GE_Users),
+			Date1:   time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+			Date2:   time.Date(2018, 8, 31, 0, 0, 0, 0, time.UTC),
+		},
+		A: ComparisonSegment{
+			Filters: "ym:ge:operatingSystem=='ios'",
+			Date1:   time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+			Date2:   time.Date(2018, 8, 7, 0, 0, 0, 0, time.UTC),
+		},
+		B: ComparisonSegment{
+			Filters: "ym:ge:operatingSystem=='android'",
+		},
+	}
+
+	if _, err := newTestClient(server).ReportComparison(context.Background(), query); err != nil {
+		t.Fatalf("failed to request report: %s", err)
+	}
+
+	var expected = map[string]string{
+		"filters_a": "ym:ge:operatingSystem=='ios'",
+		"date1_a":   "2018-08-01",
+		"date2_a":   "2018-08-07",
+		"filters_b": "ym:ge:operatingSystem=='android'",
+	}
+
+	for name, value := range expected {
+		if args[name] != value {
+			t.Errorf("wrong %s: %q", name, args[name])
+		}
+	}
+
+	// Zero dates of segment mean date range of query.
+	if _, ok := args["date1_b"]; ok {
+		t.Errorf("zero date of segment is sent: %q", args["date1_b"])
+	}
+
+	if _, ok := args["date2_b"]; ok {
+		t.Errorf("zero date of segment is sent: %q", args["date2_b"])
+	}
+}
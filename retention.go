@@ -0,0 +1,322 @@
+package appmetrica
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionQuery describes day-N retention analysis of install cohorts of
+// application. Cohort of date consists of users installed application on
+// that date (it is selected with filter by CohortDimension); retention of day
+// N is a share of cohort which is active (Metric) on N-th day after install.
+// Breakdown optionally splits cohorts by dimension like app version or
+// tracker; it should belong to namespace of Metric.
+type RetentionQuery struct {
+	Application     *Application
+	Since           time.Time
+	Until           time.Time
+	Days            int
+	Metric          Metric
+	CohortDimension Dimension
+	Breakdown       Dimension
+	Filters         string
+}
+
+// Defaults of RetentionQuery.
+const (
+	DefaultRetentionMetric = GE_Users
+	DefaultRetentionCohort = Dimension("ym:ge:installDate")
+)
+
+const (
+	defaultRetentionDays = 30
+	// maxRetentionSegments is a limit of breakdown rows per cohort.
+	maxRetentionSegments = 1000
+)
+
+// RetentionCohort is a row of retention matrix. Active[N] is a number of
+// users of cohort which were active on day N; cohort size is Active[0].
+// Active is shorter than Days+1 for cohorts which are too young.
+type RetentionCohort struct {
+	Date    time.Time
+	Segment string
+	Active  []float64
+}
+
+// Size returns number of users in cohort.
+func (c *RetentionCohort) Size() float64 {
+	if len(c.Active) == 0 {
+		return 0
+	}
+	return c.Active[0]
+}
+
+// Rate returns retention of day N or NaN if it is unknown.
+func (c *RetentionCohort) Rate(day int) float64 {
+	if day >= len(c.Active) || c.Size() == 0 {
+		return math.NaN()
+	}
+	return c.Active[day] / c.Size()
+}
+
+// RetentionMatrix is a day-N retention of install cohorts ordered by date and
+// segment.
+type RetentionMatrix struct {
+	Days    int
+	Cohorts []RetentionCohort
+}
+
+// Retention строит матрицу удержания по когортам установок: для каждой даты
+// установки выполняется запрос временного ряда активных пользователей
+// когорты за последующие Days дней.
+func (c *Client) Retention(ctx context.Context, query *RetentionQuery) (*RetentionMatrix, error) {
+	if query.Application == nil {
+		return nil, errors.New(prefix + "application of retention is not specified")
+	}
+
+	if query.Since.IsZero() || query.Until.IsZero() {
+		return nil, errors.New(prefix + "retention date range is not specified")
+	}
+
+	var loc, err = query.Application.Location()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var days = query.Days
+	var metric = query.Metric
+	var cohort = query.CohortDimension
+
+	if days <= 0 {
+		days = defaultRetentionDays
+	}
+
+	if metric == "" {
+		metric = DefaultRetentionMetric
+	}
+
+	if cohort == "" {
+		cohort = DefaultRetentionCohort
+	}
+
+	var matrix = &RetentionMatrix{Days: days}
+	var today = truncateDay(time.Now().In(loc))
+	var first = truncateDay(query.Since.In(loc))
+	var last = truncateDay(query.Until.In(loc))
+
+	if last.Before(first) {
+		return nil, errors.New(prefix + "retention date range is empty")
+	}
+
+	if last.After(today) {
+		last = today
+	}
+
+	for date := first; !date.After(last); date = date.AddDate(0, 0, 1) {
+		var until = date.AddDate(0, 0, days)
+
+		if until.After(today) {
+			until = today
+		}
+
+		var filters = Eq(string(cohort), date.Format(ReportDateFormat)).String()
+
+		if query.Filters != "" {
+			filters = "(" + query.Filters + ") AND " + filters
+		}
+
+		var subquery = &ByTimeQuery{
+			ReportQuery: ReportQuery{
+				IDs:     []int{int(query.Application.ID)},
+				Metrics: Metrics(metric),
+				Date1:   date,
+				Date2:   until,
+				Filters: filters,
+				Limit:   maxRetentionSegments,
+			},
+			Group:    RG_Day,
+			Location: loc,
+		}
+
+		if query.Breakdown != "" {
+			subquery.Dimensions = Dimensions(query.Breakdown)
+		}
+
+		var report *ByTimeReport
+
+		if report, err = c.ReportByTime(ctx, subquery); err != nil {
+			return nil, err
+		}
+
+		matrix.Cohorts = append(matrix.Cohorts, retentionCohorts(date, report)...)
+	}
+
+	matrix.sort()
+	return matrix, nil
+}
+
+// retentionCohorts converts time series of cohort activity to cohorts. Every
+// row of report is a segment of cohort; series starts on the day of install.
+func retentionCohorts(date time.Time, report *ByTimeReport) []RetentionCohort {
+	var cohorts []RetentionCohort
+
+	for _, row := range report.Data {
+		if len(row.Metrics) == 0 {
+			continue
+		}
+
+		var cohort = RetentionCohort{Date: date}
+		var names = make([]string, len(row.Dimensions))
+
+		for i, dimension := range row.Dimensions {
+			names[i] = dimension.Name
+		}
+
+		cohort.Segment = strings.Join(names, ", ")
+
+		// Align series to day of install in case API skips leading empty
+		// intervals.
+		for i, bucket := range report.Buckets {
+			if day := daysBetween(date, bucket); day >= 0 {
+				for len(cohort.Active) < day {
+					cohort.Active = append(cohort.Active, 0)
+				}
+				cohort.Active = append(cohort.Active, row.Metrics[0][i])
+			}
+		}
+
+		cohorts = append(cohorts, cohort)
+	}
+
+	return cohorts
+}
+
+func (m *RetentionMatrix) sort() {
+	sort.SliceStable(m.Cohorts, func(i, j int) bool {
+		var a, b = &m.Cohorts[i], &m.Cohorts[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Segment < b.Segment
+	})
+}
+
+// segmented reports whether cohorts are split by breakdown dimension.
+func (m *RetentionMatrix) segmented() bool {
+	for _, cohort := range m.Cohorts {
+		if cohort.Segment != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// header returns names of columns of rendered matrix.
+func (m *RetentionMatrix) header() []string {
+	var header = []string{"cohort"}
+
+	if m.segmented() {
+		header = append(header, "segment")
+	}
+
+	header = append(header, "size")
+
+	for day := 0; day <= m.Days; day++ {
+		header = append(header, "day_"+strconv.Itoa(day))
+	}
+
+	return header
+}
+
+// rows renders cells of matrix with rate formatter. Unknown rates are empty.
+func (m *RetentionMatrix) rows(format func(float64) string) [][]string {
+	var rows = make([][]string, len(m.Cohorts))
+
+	for i := range m.Cohorts {
+		var cohort = &m.Cohorts[i]
+		var row = []string{cohort.Date.Format(ReportDateFormat)}
+
+		if m.segmented() {
+			row = append(row, cohort.Segment)
+		}
+
+		row = append(row, strconv.FormatFloat(cohort.Size(), 'f', -1, 64))
+
+		for day := 0; day <= m.Days; day++ {
+			if rate := cohort.Rate(day); math.IsNaN(rate) {
+				row = append(row, "")
+			} else {
+				row = append(row, format(rate))
+			}
+		}
+
+		rows[i] = row
+	}
+
+	return rows
+}
+
+// WriteCSV writes matrix in CSV format. Retention rates are fractions.
+func (m *RetentionMatrix) WriteCSV(w io.Writer) error {
+	var writer = csv.NewWriter(w)
+	writer.Write(m.header())
+
+	var rows = m.rows(func(rate float64) string {
+		return strconv.FormatFloat(rate, 'f', 4, 64)
+	})
+
+	writer.WriteAll(rows)
+	return writer.Error()
+}
+
+// WriteMarkdown writes matrix as Markdown table. Retention rates are
+// percents.
+func (m *RetentionMatrix) WriteMarkdown(w io.Writer) error {
+	var header = m.header()
+	var separator = make([]string, len(header))
+
+	for i := range separator {
+		separator[i] = "---"
+	}
+
+	var lines = []string{
+		"| " + strings.Join(header, " | ") + " |",
+		"|" + strings.Join(separator, "|") + "|",
+	}
+
+	var rows = m.rows(func(rate float64) string {
+		return fmt.Sprintf("%.1f%%", 100*rate)
+	})
+
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = strings.Replace(cell, "|", `\|`, -1)
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+	}
+
+	var _, err = io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// truncateDay returns midnight of day of t in its location.
+func truncateDay(t time.Time) time.Time {
+	var year, month, day = t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween returns number of calendar days from a to b.
+func daysBetween(a, b time.Time) int {
+	var from = time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	var to = time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
@@ -0,0 +1,139 @@
+package appmetrica
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetention(t *testing.T) {
+	date := time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC)
+	report := &ByTimeReport{
+		Buckets: []time.Time{date, date.AddDate(0, 0, 1), date.AddDate(0, 0, 2)},
+		Data: []ByTimeRow{
+			{Dimensions: []ReportDimension{{Name: "1.1"}}, Metrics: [][]float64{{50, 20, 10}}},
+			{Dimensions: []ReportDimension{{Name: "1.0"}}, Metrics: [][]float64{{200, 100, 50}}},
+		},
+	}
+
+	matrix := &RetentionMatrix{Days: 3, Cohorts: retentionCohorts(date, report)}
+	matrix.sort()
+
+	if cohort := matrix.Cohorts[0]; cohort.Segment != "1.0" || cohort.Size() != 200 || cohort.Rate(1) != 0.5 {
+		t.Errorf("wrong cohort: %+v", cohort)
+	}
+
+	var buffer bytes.Buffer
+	if err := matrix.WriteCSV(&buffer); err != nil {
+		t.Fatalf("failed to write csv: %s", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if lines[0] != "cohort,segment,size,day_0,day_1,day_2,day_3" {
+		t.Errorf("wrong csv header: %s", lines[0])
+	}
+
+	if lines[1] != "2018-08-01,1.0,200,1.0000,0.5000,0.2500," {
+		t.Errorf("wrong csv row: %s", lines[1])
+	}
+
+	buffer.Reset()
+	if err := matrix.WriteMarkdown(&buffer); err != nil {
+		t.Fatalf("failed to write markdown: %s", err)
+	}
+
+	lines = strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 4 || lines[3] != "| 2018-08-01 | 1.1 | 50 | 100.0% | 40.0% | 20.0% |  |" {
+		t.Errorf("wrong markdown table:\n%s", buffer.String())
+	}
+}
+
+func TestClientRetention(t *testing.T) {
+	var requests []url.Values
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var args = r.URL.Query()
+		requests = append(requests, args)
+
+		// Every segment of cohort halves daily.
+		var report ByTimeReport
+		var date1, _ = time.Parse(ReportDateFormat, args.Get("date1"))
+		var date2, _ = time.Parse(ReportDateFormat, args.Get("date2"))
+		var series []float64
+
+		for date, value := date1, 100.0; !date.After(date2); date, value = date.AddDate(0, 0, 1), value/2 {
+			var day = date.Format(ReportDateFormat)
+			report.TimeIntervals = append(report.TimeIntervals, []string{day, day})
+			series = append(series, value)
+		}
+
+		for _, version := range []string{"1.1", "1.0"} {
+			report.Data = append(report.Data, ByTimeRow{
+				Dimensions: []ReportDimension{{ID: version, Name: version}},
+				Metrics:    [][]float64{series},
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/x-yametrika+json")
+		json.NewEncoder(w).Encode(&report)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	query := &RetentionQuery{
+		Application: &Application{ID: 84126, TimeZoneName: "UTC"},
+		Since:       time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+		Until:       time.Date(2018, 8, 2, 12, 0, 0, 0, time.UTC),
+		Days:        2,
+		Breakdown:   GE_AppVersion,
+		Filters:     "ym:ge:operatingSystem=='ios'",
+	}
+
+	matrix, err := client.Retention(context.Background(), query)
+	if err != nil {
+		t.Fatalf("failed to build retention matrix: %s", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected request per cohort instead of %d requests", len(requests))
+	}
+
+	for i, args := range requests {
+		var date = query.Since.AddDate(0, 0, i).Format(ReportDateFormat)
+		var filters = "(ym:ge:operatingSystem=='ios') AND ym:ge:installDate=='" + date + "'"
+
+		if args.Get("filters") != filters {
+			t.Errorf("wrong filters of cohort %s: %s", date, args.Get("filters"))
+		}
+
+		if args.Get("date1") != date || args.Get("group") != "day" ||
+			args.Get("metrics") != "ym:ge:users" || args.Get("dimensions") != "ym:ge:appVersion" {
+			t.Errorf("wrong query of cohort %s: %v", date, args)
+		}
+	}
+
+	if len(matrix.Cohorts) != 4 {
+		t.Fatalf("expected 4 cohorts instead of %d", len(matrix.Cohorts))
+	}
+
+	if cohort := matrix.Cohorts[1]; cohort.Segment != "1.1" || cohort.Date.Day() != 1 ||
+		cohort.Size() != 100 || cohort.Rate(2) != 0.25 {
+		t.Errorf("wrong cohort: %+v", cohort)
+	}
+
+	for _, invalid := range []RetentionQuery{
+		{Application: query.Application, Since: query.Since},
+		{Application: query.Application, Until: query.Until},
+		{Application: query.Application, Since: query.Until, Until: query.Since},
+	} {
+		if _, err := client.Retention(context.Background(), &invalid); err == nil {
+			t.Errorf("invalid date range was accepted: %s..%s", invalid.Since, invalid.Until)
+		}
+	}
+}
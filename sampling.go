@@ -0,0 +1,78 @@
+package appmetrica
+
+import (
+	"errors"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ErrSampled is returned by Reporting API queries when result is computed on
+// sample which is smaller than sampling policy of client allows.
+var ErrSampled = errors.New(prefix + "report is sampled below threshold")
+
+// SamplingPolicy controls sampling of Reporting API results. Accuracy is used
+// by queries which do not specify one; RA_Full asks API not to sample data
+// whenever it is possible. Results which are sampled with share less than
+// MinShare are passed to Warn if it is set; otherwise query fails with
+// ErrSampled. Zero MinShare disables the check.
+type SamplingPolicy struct {
+	Accuracy ReportAccuracy
+	MinShare float64
+	Warn     func(path string, sampling ReportSampling)
+}
+
+// SetSamplingPolicy sets sampling policy of Reporting API queries.
+func (c *Client) SetSamplingPolicy(policy SamplingPolicy) error {
+	if err := policy.Accuracy.validate(); err != nil {
+		return err
+	}
+
+	if policy.MinShare < 0 || policy.MinShare > 1 {
+		return errors.New(prefix + "minimal sample share is out of range")
+	}
+
+	c.sampling = policy
+	return nil
+}
+
+// sampledReport is implemented by all reports through embedded
+// ReportSampling.
+type sampledReport interface {
+	sampling() ReportSampling
+}
+
+func (s *ReportSampling) sampling() ReportSampling {
+	return *s
+}
+
+// applyAccuracy sets default accuracy of policy if request has none. It
+// should be called before cache key is computed.
+func (c *Client) applyAccuracy(req *fasthttp.Request) {
+	var args = req.URI().QueryArgs()
+
+	if c.sampling.Accuracy != "" && !args.Has(`accuracy`) {
+		args.Set(`accuracy`, string(c.sampling.Accuracy))
+	}
+}
+
+// checkSampling applies sampling policy to decoded report.
+func (c *Client) checkSampling(req *fasthttp.Request, obj interface{}) error {
+	var report, ok = obj.(sampledReport)
+
+	if !ok || c.sampling.MinShare == 0 {
+		return nil
+	}
+
+	var sampling = report.sampling()
+
+	if !sampling.Sampled || sampling.SampleShare >= c.sampling.MinShare {
+		return nil
+	}
+
+	if c.sampling.Warn != nil {
+		c.sampling.Warn(string(req.URI().Path()), sampling)
+		return nil
+	}
+
+	return ErrSampled
+}
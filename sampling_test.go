@@ -0,0 +1,97 @@
+package appmetrica
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSamplingPolicy(t *testing.T) {
+	var accuracy string
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accuracy = r.URL.Query().Get("accuracy")
+		w.Header().Set("Content-Type", "application/x-yametrika+json")
+		w.Write([]byte(`{
+			"query": {"ids": [84126], "metrics": ["ym:ge:users"], "accuracy": 0.1},
+			"data": [],
+			"total_rows": 0,
+			"sampled": true,
+			"sample_share": 0.1,
+			"sample_size": 1000,
+			"sample_space": 10000
+		}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	query := &ReportQuery{
+		IDs:     []int{84126},
+		Metrics: Metrics(GE_Users),
+		Date1:   time.Now(),
+		Date2:   time.Now(),
+	}
+
+	report, err := client.Report(context.Background(), query)
+	if err != nil {
+		t.Fatalf("failed to request report: %s", err)
+	}
+
+	if !report.Sampled || report.SampleShare != 0.1 || report.SampleSize != 1000 ||
+		report.SampleSpace != 10000 || report.Query.Accuracy != "0.1" {
+		t.Errorf("unexpected sampling of report: %+v (accuracy %q)",
+			report.ReportSampling, report.Query.Accuracy)
+	}
+
+	if accuracy != "" {
+		t.Errorf("accuracy should not be requested: %q", accuracy)
+	}
+
+	if err := client.SetSamplingPolicy(SamplingPolicy{MinShare: 2}); err == nil {
+		t.Errorf("minimal share out of range should be rejected")
+	}
+
+	if err := client.SetSamplingPolicy(SamplingPolicy{Accuracy: RA_Full, MinShare: 0.5}); err != nil {
+		t.Fatalf("failed to set sampling policy: %s", err)
+	}
+
+	if _, err := client.Report(context.Background(), query); err != ErrSampled {
+		t.Errorf("sampled report should fail: %v", err)
+	}
+
+	if accuracy != string(RA_Full) {
+		t.Errorf("accuracy of policy should be requested: %q", accuracy)
+	}
+
+	query.Accuracy = RA_Low
+
+	if _, err := client.Report(context.Background(), query); err != ErrSampled {
+		t.Errorf("sampled report should fail: %v", err)
+	}
+
+	if accuracy != string(RA_Low) {
+		t.Errorf("accuracy of query should take precedence: %q", accuracy)
+	}
+
+	var warnings []ReportSampling
+
+	client.SetSamplingPolicy(SamplingPolicy{
+		MinShare: 0.5,
+		Warn: func(path string, sampling ReportSampling) {
+			if path != "/stat/v1/data" {
+				t.Errorf("unexpected path of sampled report: %s", path)
+			}
+			warnings = append(warnings, sampling)
+		},
+	})
+
+	if _, err := client.Report(context.Background(), query); err != nil {
+		t.Errorf("sampled report should only be warned about: %s", err)
+	}
+
+	if len(warnings) != 1 || warnings[0].SampleShare != 0.1 {
+		t.Errorf("unexpected warnings: %+v", warnings)
+	}
+}
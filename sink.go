@@ -0,0 +1,450 @@
+package appmetrica
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+)
+
+// Sink consumes rows of export. Fields are the same for all rows written to
+// sink.
+type Sink interface {
+	WriteRow(fields, values []string) error
+	Close() error
+}
+
+// CopyRows writes all rows of reader to sink and returns number of written
+// rows. Neither reader nor sink is closed.
+func CopyRows(sink Sink, reader *ExportReader) (int64, error) {
+	var count int64
+
+	for reader.Next() {
+		if err := sink.WriteRow(reader.Fields(), reader.Values()); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, reader.Err()
+}
+
+// SinkFormat is a format of files produced by file sinks.
+type SinkFormat string
+
+const (
+	SF_NDJSON SinkFormat = "ndjson"
+	SF_CSV    SinkFormat = "csv"
+)
+
+// NewFormatSink creates sink which writes rows to w in specified format.
+func NewFormatSink(w io.Writer, format SinkFormat) Sink {
+	if format == SF_CSV {
+		return NewCSVSink(w)
+	}
+	return NewNDJSONSink(w)
+}
+
+// NDJSONSink writes every row as JSON object on separate line. Values are
+// written as strings.
+type NDJSONSink struct {
+	writer *bufio.Writer
+	buffer []byte
+}
+
+func NewNDJSONSink(w io.Writer) *NDJSONSink {
+	return &NDJSONSink{writer: bufio.NewWriter(w)}
+}
+
+func (s *NDJSONSink) WriteRow(fields, values []string) error {
+	s.buffer = append(s.buffer[:0], '{')
+
+	for i, field := range fields {
+		if i > 0 {
+			s.buffer = append(s.buffer, ',')
+		}
+
+		var key, _ = json.Marshal(field)
+		var value, _ = json.Marshal(values[i])
+
+		s.buffer = append(s.buffer, key...)
+		s.buffer = append(s.buffer, ':')
+		s.buffer = append(s.buffer, value...)
+	}
+
+	s.buffer = append(s.buffer, '}', '\n')
+
+	var _, err = s.writer.Write(s.buffer)
+	return err
+}
+
+// Flush writes buffered rows to underlying writer.
+func (s *NDJSONSink) Flush() error {
+	return s.writer.Flush()
+}
+
+// Close flushes buffered rows. It does not close underlying writer.
+func (s *NDJSONSink) Close() error {
+	return s.Flush()
+}
+
+// CSVSink writes rows in CSV format with header line.
+type CSVSink struct {
+	writer *csv.Writer
+	header bool
+}
+
+func NewCSVSink(w io.Writer) *CSVSink {
+	return &CSVSink{writer: csv.NewWriter(w)}
+}
+
+func (s *CSVSink) WriteRow(fields, values []string) error {
+	if !s.header {
+		s.header = true
+
+		if err := s.writer.Write(fields); err != nil {
+			return err
+		}
+	}
+
+	return s.writer.Write(values)
+}
+
+// Flush writes buffered rows to underlying writer.
+func (s *CSVSink) Flush() error {
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+// Close flushes buffered rows. It does not close underlying writer.
+func (s *CSVSink) Close() error {
+	return s.Flush()
+}
+
+// FileSinkOptions configures files produced by RotatingSink. File is rotated
+// as soon as its uncompressed size exceeds MaxSize or it has been open for
+// MaxAge. Zero values disable corresponding rotation.
+type FileSinkOptions struct {
+	Format  SinkFormat
+	Gzip    bool
+	MaxSize int64
+	MaxAge  time.Duration
+}
+
+// RotatingSink writes rows to sequence of files in directory. Files are
+// named <name>-<number>.<format>[.gz] and become visible under these names
+// only when they are completed.
+type RotatingSink struct {
+	dir     string
+	name    string
+	options FileSinkOptions
+	number  int
+
+	file    *os.File
+	buffer  *bufio.Writer
+	gzip    *gzip.Writer
+	counter *countingWriter
+	sink    Sink
+	opened  time.Time
+}
+
+// NewRotatingSink creates sink which writes files to directory. Numbering of
+// files continues after already existing ones.
+func NewRotatingSink(dir, name string, options FileSinkOptions) *RotatingSink {
+	if options.Format == "" {
+		options.Format = SF_NDJSON
+	}
+	return &RotatingSink{dir: dir, name: name, options: options}
+}
+
+func (s *RotatingSink) WriteRow(fields, values []string) error {
+	if s.sink != nil && s.expired() {
+		if err := s.finish(); err != nil {
+			return err
+		}
+	}
+
+	if s.sink == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.sink.WriteRow(fields, values); err != nil {
+		return err
+	}
+
+	// Flush format sink in order to count size of file precisely. Written
+	// data are still buffered by compressor or file buffer.
+	if s.options.MaxSize > 0 {
+		if flusher, ok := s.sink.(interface{ Flush() error }); ok {
+			return flusher.Flush()
+		}
+	}
+
+	return nil
+}
+
+func (s *RotatingSink) Close() error {
+	if s.sink == nil {
+		return nil
+	}
+	return s.finish()
+}
+
+func (s *RotatingSink) expired() bool {
+	if s.options.MaxAge > 0 && time.Since(s.opened) >= s.options.MaxAge {
+		return true
+	}
+
+	return s.options.MaxSize > 0 && s.counter.count >= s.options.MaxSize
+}
+
+func (s *RotatingSink) filename(number int) string {
+	var name = s.name + "-" + fmt.Sprintf("%06d", number) + "." + string(s.options.Format)
+
+	if s.options.Gzip {
+		name += ".gz"
+	}
+
+	return filepath.Join(s.dir, name)
+}
+
+func (s *RotatingSink) open() error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	// Skip numbers of files which already exist.
+	for {
+		s.number++
+
+		if _, err := os.Stat(s.filename(s.number)); os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	var path = s.filename(s.number)
+	var file, err = os.Create(filepath.Join(s.dir, "."+filepath.Base(path)+".tmp"))
+
+	if err != nil {
+		return err
+	}
+
+	s.file = file
+	s.buffer = bufio.NewWriterSize(file, 64<<10)
+	s.opened = time.Now()
+
+	var writer io.Writer = s.buffer
+
+	if s.options.Gzip {
+		s.gzip = gzip.NewWriter(s.buffer)
+		writer = s.gzip
+	}
+
+	s.counter = &countingWriter{writer: writer}
+	s.sink = NewFormatSink(s.counter, s.options.Format)
+	return nil
+}
+
+// finish flushes and closes current file and moves it to its final name.
+func (s *RotatingSink) finish() error {
+	var err = s.sink.Close()
+
+	if s.gzip != nil {
+		if gzipErr := s.gzip.Close(); err == nil {
+			err = gzipErr
+		}
+	}
+
+	if flushErr := s.buffer.Flush(); err == nil {
+		err = flushErr
+	}
+
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(s.file.Name(), s.filename(s.number))
+	}
+
+	s.file, s.buffer, s.gzip, s.counter, s.sink = nil, nil, nil, nil, nil
+	return err
+}
+
+// countingWriter counts bytes written through it.
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+}
+
+func (w *countingWriter) Write(buffer []byte) (int, error) {
+	var written, err = w.writer.Write(buffer)
+	w.count += int64(written)
+	return written, err
+}
+
+// PartitionedSink routes rows to rotating sinks in directory tree
+// <dir>/application_id=<id>/date=<yyyy-mm-dd>/. Date of row is taken from
+// time field of resource (e.g. event_datetime for events); resources without
+// time field are partitioned by the default date. At most maxPartitions
+// partitions are open at once: the least recently written one is closed when
+// another one is opened and its rows which come later are written to a new
+// file.
+type PartitionedSink struct {
+	dir      string
+	id       int
+	resource ExportResource
+	date     string
+	options  FileSinkOptions
+	column   int
+	sinks    map[string]*RotatingSink
+	recent   []string // dates of open partitions from the least recent one
+}
+
+// maxPartitions is a number of partitions which PartitionedSink keeps open.
+// Rows of chunked export are ordered by windows so that only partitions of
+// adjacent dates are written at once.
+const maxPartitions = 4
+
+// NewPartitionedSink creates partitioned sink for rows of application
+// resource. Default date is used for rows which have no date.
+func NewPartitionedSink(dir string, id int, resource ExportResource, date time.Time, options FileSinkOptions) *PartitionedSink {
+	return &PartitionedSink{
+		dir:      dir,
+		id:       id,
+		resource: resource,
+		date:     date.Format("2006-01-02"),
+		options:  options,
+		column:   -1,
+		sinks:    make(map[string]*RotatingSink),
+	}
+}
+
+func (s *PartitionedSink) WriteRow(fields, values []string) error {
+	if s.column == -1 {
+		s.column = len(fields)
+
+		for i, field := range fields {
+			if field == timeFields[s.resource] {
+				s.column = i
+			}
+		}
+	}
+
+	var date = s.date
+
+	if s.column < len(values) && len(values[s.column]) >= len("2006-01-02") {
+		date = values[s.column][:len("2006-01-02")]
+	}
+
+	var sink, ok = s.sinks[date]
+
+	if ok {
+		s.touch(date)
+	} else {
+		if len(s.recent) >= maxPartitions {
+			var oldest = s.recent[0]
+			var err = s.sinks[oldest].Close()
+
+			delete(s.sinks, oldest)
+			s.recent = s.recent[1:]
+
+			if err != nil {
+				return err
+			}
+		}
+
+		var dir = filepath.Join(s.dir, "application_id="+strconv.Itoa(s.id), "date="+date)
+		sink = NewRotatingSink(dir, string(s.resource), s.options)
+		s.sinks[date] = sink
+		s.recent = append(s.recent, date)
+	}
+
+	return sink.WriteRow(fields, values)
+}
+
+// touch moves date of open partition to the end of recently written ones.
+func (s *PartitionedSink) touch(date string) {
+	var last = len(s.recent) - 1
+
+	if s.recent[last] == date {
+		return
+	}
+
+	for i := range s.recent {
+		if s.recent[i] == date {
+			copy(s.recent[i:], s.recent[i+1:])
+			s.recent[last] = date
+			return
+		}
+	}
+}
+
+func (s *PartitionedSink) Close() error {
+	var err error
+
+	for date, sink := range s.sinks {
+		if closeErr := sink.Close(); err == nil {
+			err = closeErr
+		}
+		delete(s.sinks, date)
+	}
+
+	s.recent = nil
+	return err
+}
+
+// Dump exports query with chunked exporter and writes rows to directory tree
+// partitioned by application and date (see PartitionedSink). Time field of
+// resource is added to field list if it is missing. It returns number of
+// written rows.
+func (e *ChunkedExporter) Dump(ctx context.Context, query *ExportQuery, dir string, options FileSinkOptions) (int64, error) {
+	var subquery = *query
+	subquery.Fields = expandFields(query.Resource, query.Fields)
+
+	if field, ok := timeFields[query.Resource]; ok {
+		var found bool
+
+		for _, name := range subquery.Fields {
+			found = found || name == field
+		}
+
+		if !found {
+			var length = len(subquery.Fields)
+			subquery.Fields = append(subquery.Fields[:length:length], field)
+		}
+	}
+
+	var reader, err = e.Export(ctx, &subquery)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer reader.Close()
+
+	// Default date is the first day of export in time zone of API.
+	var since = subquery.Since.In(subquery.location())
+	var sink = NewPartitionedSink(dir, query.ApplicationID, query.Resource, since, options)
+	var count int64
+
+	count, err = CopyRows(sink, reader)
+
+	if closeErr := sink.Close(); err == nil {
+		err = closeErr
+	}
+
+	return count, err
+}
@@ -0,0 +1,151 @@
+package appmetrica
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appmetrica-sink-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	options := FileSinkOptions{Format: SF_NDJSON, Gzip: true, MaxSize: 1}
+	since := time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC)
+	sink := NewPartitionedSink(dir, 84126, ER_Events, since, options)
+	fields := []string{"event_name", "event_datetime"}
+
+	rows := [][]string{
+		{"launch", "2018-08-01 10:00:00"},
+		{"close", "2018-08-01 11:00:00"},
+		{"launch \"quoted\"", "2018-08-02 09:00:00"},
+	}
+
+	for _, row := range rows {
+		if err := sink.WriteRow(fields, row); err != nil {
+			t.Fatalf("failed to write row: %s", err)
+		}
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("failed to close sink: %s", err)
+	}
+
+	pattern := filepath.Join(dir, "application_id=84126", "date=*", "events-*.ndjson.gz")
+	files, _ := filepath.Glob(pattern)
+
+	// Every row should be in its own file due to size limit.
+	if len(files) != 3 {
+		t.Fatalf("wrong number of files: %v", files)
+	}
+
+	file, err := os.Open(files[2])
+	if err != nil {
+		t.Fatalf("failed to open file: %s", err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("failed to read gzip file: %s", err)
+	}
+
+	content, _ := ioutil.ReadAll(reader)
+	expected := `{"event_name":"launch \"quoted\"","event_datetime":"2018-08-02 09:00:00"}`
+
+	if line := strings.TrimSpace(string(content)); line != expected {
+		t.Errorf("wrong content of file: %s", line)
+	}
+
+	if !strings.Contains(files[2], "date=2018-08-02") {
+		t.Errorf("wrong partition of file: %s", files[2])
+	}
+}
+
+func TestPartitionedSinkLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appmetrica-sink-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	since := time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC)
+	sink := NewPartitionedSink(dir, 84126, ER_Events, since, FileSinkOptions{})
+	fields := []string{"event_name", "event_datetime"}
+
+	// The first day is written again after the others have evicted it.
+	for _, day := range []int{1, 2, 1, 3, 4, 5, 6, 1} {
+		row := []string{"launch", fmt.Sprintf("2018-08-%02d 10:00:00", day)}
+		if err := sink.WriteRow(fields, row); err != nil {
+			t.Fatalf("failed to write row: %s", err)
+		}
+
+		if len(sink.sinks) > maxPartitions {
+			t.Fatalf("too many open partitions: %d", len(sink.sinks))
+		}
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("failed to close sink: %s", err)
+	}
+
+	pattern := filepath.Join(dir, "application_id=84126", "date=2018-08-01", "events-*.ndjson")
+	files, _ := filepath.Glob(pattern)
+
+	if len(files) != 2 {
+		t.Errorf("evicted partition should be continued in new file: %v", files)
+	}
+}
+
+func TestDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "appmetrica-dump-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := newChunkedServer(func(w http.ResponseWriter, window, attempt int) {
+		fmt.Fprintln(w, "event_datetime,event_name")
+		fmt.Fprintf(w, "2018-08-%02d 10:00:00,w%d\n", window+1, window)
+	})
+	defer server.Close()
+
+	query := &ExportQuery{
+		ApplicationID: 84126,
+		Resource:      ER_Events,
+		Fields:        []string{"event_name"},
+		Since:         time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2018, 8, 4, 23, 59, 59, 0, time.UTC),
+	}
+
+	count, err := server.exporter(2).Dump(context.Background(), query, dir, FileSinkOptions{})
+	if err != nil {
+		t.Fatalf("failed to dump export: %s", err)
+	}
+
+	if count != 4 {
+		t.Errorf("wrong number of rows: %d", count)
+	}
+
+	pattern := filepath.Join(dir, "application_id=84126", "date=*", "events-*.ndjson")
+	files, _ := filepath.Glob(pattern)
+
+	// Rows are partitioned by time field though it is not requested.
+	if len(files) != 4 {
+		t.Errorf("wrong partitions of rows: %v", files)
+	}
+
+	if len(query.Fields) != 1 {
+		t.Errorf("fields of query were modified: %v", query.Fields)
+	}
+}
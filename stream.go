@@ -0,0 +1,199 @@
+package appmetrica
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http/httputil"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// maxErrorBodySize limits size of unsuccessful response body which is read
+// in order to build error message.
+const maxErrorBodySize = 64 << 10 // 64kb
+
+// defaultStreamTimeout limits time of waiting for data on connection if
+// client has no read timeout.
+const defaultStreamTimeout = time.Minute
+
+// stream sends request over dedicated connection and reads response header
+// into res. Unlike fasthttp.Client it does not buffer response body, so
+// memory consumption does not depend on body size. Caller reads body from
+// returned reader and has to close it in order to release connection.
+// Connection is dialed and secured with Dial and TLSConfig of fasthttp.Client;
+// every read has to complete within its ReadTimeout.
+func (c *Client) stream(ctx context.Context, req *fasthttp.Request, res *fasthttp.Response) (io.ReadCloser, error) {
+	var uri = req.URI()
+	var host = string(uri.Host())
+	var addr = host
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		if string(uri.Scheme()) == "https" {
+			addr = host + ":443"
+		} else {
+			addr = host + ":80"
+		}
+	}
+
+	var conn, err = c.dial(addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if string(uri.Scheme()) == "https" {
+		var config = new(tls.Config)
+
+		if c.client.TLSConfig != nil {
+			config = c.client.TLSConfig.Clone()
+		}
+
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+
+		conn = tls.Client(conn, config)
+	}
+
+	var body = &streamBody{
+		ctx:     ctx,
+		conn:    conn,
+		timeout: c.client.ReadTimeout,
+		done:    make(chan struct{}),
+	}
+
+	if body.timeout <= 0 {
+		body.timeout = defaultStreamTimeout
+	}
+
+	go body.watch()
+	body.extend()
+
+	req.SetConnectionClose()
+
+	var writer = bufio.NewWriter(conn)
+
+	if err = req.Write(writer); err == nil {
+		err = writer.Flush()
+	}
+
+	if err != nil {
+		body.Close()
+		return nil, body.wrap(err)
+	}
+
+	var reader = bufio.NewReaderSize(conn, 64<<10)
+
+	if err = res.Header.Read(reader); err != nil {
+		body.Close()
+		return nil, body.wrap(err)
+	}
+
+	switch length := res.Header.ContentLength(); {
+	case length >= 0:
+		body.reader = &sizedReader{reader, int64(length)}
+	case length == -1:
+		body.reader = httputil.NewChunkedReader(reader)
+	default:
+		body.reader = reader
+	}
+
+	return body, nil
+}
+
+// dial connects to address in the same way as fasthttp.Client does.
+func (c *Client) dial(addr string) (net.Conn, error) {
+	switch {
+	case c.client.Dial != nil:
+		return c.client.Dial(addr)
+	case c.client.DialDualStack:
+		return fasthttp.DialDualStack(addr)
+	default:
+		return fasthttp.Dial(addr)
+	}
+}
+
+// sizedReader reads body of known length. Unlike io.LimitReader it reports
+// connection which is closed before the end of body as an error.
+type sizedReader struct {
+	reader io.Reader
+	left   int64
+}
+
+func (r *sizedReader) Read(buffer []byte) (int, error) {
+	if r.left <= 0 {
+		return 0, io.EOF
+	}
+
+	if int64(len(buffer)) > r.left {
+		buffer = buffer[:r.left]
+	}
+
+	var read, err = r.reader.Read(buffer)
+	r.left -= int64(read)
+
+	if err == io.EOF && r.left > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return read, err
+}
+
+// streamBody is a response body which is read directly from connection. It
+// closes connection as soon as context is done.
+type streamBody struct {
+	ctx     context.Context
+	conn    net.Conn
+	reader  io.Reader
+	timeout time.Duration
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (b *streamBody) Read(buffer []byte) (int, error) {
+	b.extend()
+	var read, err = b.reader.Read(buffer)
+	return read, b.wrap(err)
+}
+
+// extend moves deadline of connection timeout ahead but not beyond deadline
+// of context so that stalled connection does not block reader forever.
+func (b *streamBody) extend() {
+	var deadline = time.Now().Add(b.timeout)
+
+	if limit, ok := b.ctx.Deadline(); ok && limit.Before(deadline) {
+		deadline = limit
+	}
+
+	b.conn.SetDeadline(deadline)
+}
+
+func (b *streamBody) Close() error {
+	var err error
+	b.once.Do(func() {
+		close(b.done)
+		err = b.conn.Close()
+	})
+	return err
+}
+
+func (b *streamBody) watch() {
+	select {
+	case <-b.ctx.Done():
+		b.Close()
+	case <-b.done:
+	}
+}
+
+// wrap replaces error caused by closed connection with context error.
+func (b *streamBody) wrap(err error) error {
+	if err != nil && err != io.EOF && b.ctx.Err() != nil {
+		return b.ctx.Err()
+	}
+	return err
+}
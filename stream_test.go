@@ -0,0 +1,99 @@
+package appmetrica
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestStream(t *testing.T) {
+	var stall = make(chan struct{})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/truncated" {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\nshort"))
+			conn.Close()
+			return
+		}
+
+		w.Write([]byte("first chunk\n"))
+		w.(http.Flusher).Flush()
+
+		if r.URL.Path == "/stall" {
+			<-stall
+			return
+		}
+
+		w.Write([]byte("second chunk\n"))
+	}))
+	defer server.Close()
+	defer close(stall)
+
+	client := newTestClient(server)
+	client.client.ReadTimeout = 200 * time.Millisecond
+
+	var request = func(path string) ([]byte, error) {
+		req, res := client.prepare()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(res)
+
+		req.URI().SetPath(path)
+
+		body, err := client.stream(context.Background(), req, res)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+
+		if res.StatusCode() != http.StatusOK {
+			t.Errorf("unexpected status code: %d", res.StatusCode())
+		}
+
+		return ioutil.ReadAll(body)
+	}
+
+	t.Run("Chunked", func(t *testing.T) {
+		data, err := request("/")
+		if err != nil {
+			t.Fatalf("failed to read body: %s", err)
+		}
+		if string(data) != "first chunk\nsecond chunk\n" {
+			t.Errorf("unexpected body: %q", data)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		data, err := request("/truncated")
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("truncated body should fail: %v", err)
+		}
+		if string(data) != "short" {
+			t.Errorf("unexpected body: %q", data)
+		}
+	})
+
+	t.Run("Stall", func(t *testing.T) {
+		var start = time.Now()
+		var data, err = request("/stall")
+
+		if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			t.Fatalf("stalled read should time out: %v", err)
+		}
+
+		if string(data) != "first chunk\n" {
+			t.Errorf("unexpected body before stall: %q", data)
+		}
+
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("read timeout is not applied: %s", elapsed)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package appmetrica
+
+import (
+	"errors"
+	"time"
+)
+
+// Location returns time zone of application which Logs API and Reporting API
+// use for dates. Zone is loaded by TimeZoneName; if the name is empty then
+// fixed zone with TimeZoneOffset (in seconds) is returned.
+func (a *Application) Location() (*time.Location, error) {
+	if a.TimeZoneName == "" {
+		if a.TimeZoneOffset == 0 {
+			return time.UTC, nil
+		}
+		return time.FixedZone("", a.TimeZoneOffset), nil
+	}
+
+	var loc, err = time.LoadLocation(a.TimeZoneName)
+
+	if err != nil {
+		var msg = "unknown time zone of application: " + a.TimeZoneName
+		return nil, errors.New(prefix + msg)
+	}
+
+	return loc, nil
+}
@@ -1,5 +1,10 @@
 package appmetrica
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type Application struct {
 	APIKey128             string `json:"api_key128,omitempty"`
 	CreateDate            string `json:"create_date,omitempty"`
@@ -21,20 +26,332 @@ type Application struct {
 
 type Applications []Application
 
+// Partner describes advertising partner (network) which trackers could be
+// attributed to.
+type Partner struct {
+	ID         uint64 `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	WebsiteURL string `json:"website_url,omitempty"`
+	Postbacks  bool   `json:"postbacks,omitempty"`
+}
+
+// Tracker describes tracking link of an application which is used to
+// attribute installations and events to partner.
+type Tracker struct {
+	TrackingID        uint64             `json:"tracking_id,omitempty"`
+	ApplicationID     uint64             `json:"app_id,omitempty"`
+	PartnerID         uint64             `json:"partner_id,omitempty"`
+	Name              string             `json:"name,omitempty"`
+	Status            string             `json:"status,omitempty"`
+	CreateDate        string             `json:"create_date,omitempty"`
+	TrackingURL       string             `json:"tracking_url,omitempty"`
+	Redirect          *TrackerRedirect   `json:"redirect,omitempty"`
+	Postbacks         []Postback         `json:"postbacks,omitempty"`
+	AttributionWindow *AttributionWindow `json:"attribution_window,omitempty"`
+}
+
+// TrackerRedirect specifies where user is redirected after click on tracking
+// link depending on platform.
+type TrackerRedirect struct {
+	DefaultURL string `json:"default_url,omitempty"`
+	AndroidURL string `json:"android_url,omitempty"`
+	IOSURL     string `json:"ios_url,omitempty"`
+	DeepLink   string `json:"deeplink,omitempty"`
+}
+
+// Postback describes request which AppMetrica sends to partner on attributed
+// installation or event. URL is a template which could contain macros in curly
+// braces (e.g. {ios_ifa}) substituted by AppMetrica. Nil Enabled keeps current
+// state of postback (see Bool).
+type Postback struct {
+	ID      uint64          `json:"id,omitempty"`
+	Type    PostbackType    `json:"type,omitempty"`
+	Events  []PostbackEvent `json:"events,omitempty"`
+	URL     string          `json:"url,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Body    string          `json:"body,omitempty"`
+	Enabled *bool           `json:"enabled,omitempty"`
+}
+
+// PostbackType specifies what triggers postback.
+type PostbackType string
+
+const (
+	PT_Install       PostbackType = "install"
+	PT_Reattribution PostbackType = "reattribution"
+	PT_Event         PostbackType = "event"
+)
+
+// PostbackEvent maps name of application event to name of event which is
+// known to partner.
+type PostbackEvent struct {
+	EventName        string `json:"event_name"`
+	PartnerEventName string `json:"partner_event_name,omitempty"`
+}
+
+// AttributionWindow sets up time intervals in which clicks on tracking link
+// could be attributed to installation. Nil Reattribution keeps current
+// setting (see Bool).
+type AttributionWindow struct {
+	ClickDays        int   `json:"click_days,omitempty"`
+	FingerprintHours int   `json:"fingerprint_hours,omitempty"`
+	Reattribution    *bool `json:"reattribution,omitempty"`
+	InactivityDays   int   `json:"inactivity_days,omitempty"`
+}
+
+// Bool returns pointer to value for optional flags of requests.
+func Bool(value bool) *bool {
+	return &value
+}
+
+// PushCredentials contains credentials of push notification services which
+// are used by AppMetrica to send push notifications to application.
+type PushCredentials struct {
+	APNs *APNsCredentials `json:"apns,omitempty"`
+	FCM  *FCMCredentials  `json:"fcm,omitempty"`
+}
+
+// PushPlatform identifies push notification service.
+type PushPlatform string
+
+const (
+	PP_APNs PushPlatform = "apns"
+	PP_FCM  PushPlatform = "fcm"
+)
+
+// APNsCredentials describes either certificate (.p12) or token key (.p8) of
+// Apple Push Notification service. Binary fields are transferred in base64.
+type APNsCredentials struct {
+	Environment string `json:"environment,omitempty"`
+	Certificate []byte `json:"certificate,omitempty"`
+	Password    string `json:"password,omitempty"`
+	Key         []byte `json:"key,omitempty"`
+	KeyID       string `json:"key_id,omitempty"`
+	TeamID      string `json:"team_id,omitempty"`
+	BundleID    string `json:"bundle_id,omitempty"`
+	Status      string `json:"status,omitempty"`
+	UploadDate  string `json:"upload_date,omitempty"`
+	ExpireDate  string `json:"expire_date,omitempty"`
+}
+
+// FCMCredentials describes server key of Firebase Cloud Messaging.
+type FCMCredentials struct {
+	ServerKey  string `json:"server_key,omitempty"`
+	SenderID   string `json:"sender_id,omitempty"`
+	Status     string `json:"status,omitempty"`
+	UploadDate string `json:"upload_date,omitempty"`
+	ExpireDate string `json:"expire_date,omitempty"`
+}
+
+// TestDevice describes device which statistics is excluded from reports.
+type TestDevice struct {
+	ID       uint64       `json:"id,omitempty"`
+	Name     string       `json:"name,omitempty"`
+	Type     DeviceIDType `json:"type"`
+	DeviceID string       `json:"device_id"`
+}
+
+// DeviceIDType specifies kind of identifier of test device.
+type DeviceIDType string
+
+const (
+	DT_AppMetricaDeviceID DeviceIDType = "appmetrica_device_id"
+	DT_IFA                DeviceIDType = "ios_ifa"
+	DT_GoogleAID          DeviceIDType = "google_aid"
+)
+
+// IPFilter describes range of IP addresses in CIDR notation which traffic is
+// excluded from reports.
+type IPFilter struct {
+	ID   uint64 `json:"id,omitempty"`
+	CIDR string `json:"cidr"`
+}
+
+// EventName describes name of event which has been received by application
+// and its visibility in reports.
+type EventName struct {
+	Name          string `json:"event_name"`
+	Hidden        bool   `json:"hidden"`
+	FirstSeenDate string `json:"first_seen_date,omitempty"`
+	LastSeenDate  string `json:"last_seen_date,omitempty"`
+}
+
 type Error struct {
 	Type    string `json:"error_type"`
 	Message string `json:"message"`
 }
 
 type Response struct {
-	Application  *Application  `json:"application,omitempty"`
-	Applications []Application `json:"applications,omitempty"`
+	Application     *Application     `json:"application,omitempty"`
+	Applications    []Application    `json:"applications,omitempty"`
+	Partners        []Partner        `json:"partners,omitempty"`
+	Postback        *Postback        `json:"postback,omitempty"`
+	Postbacks       []Postback       `json:"postbacks,omitempty"`
+	PushCredentials *PushCredentials `json:"push_credentials,omitempty"`
+	Tracker         *Tracker         `json:"tracker,omitempty"`
+	Trackers        []Tracker        `json:"trackers,omitempty"`
+
+	EventNames  []EventName  `json:"event_names,omitempty"`
+	IPFilter    *IPFilter    `json:"ip_filter,omitempty"`
+	IPFilters   []IPFilter   `json:"ip_filters,omitempty"`
+	TestDevice  *TestDevice  `json:"test_device,omitempty"`
+	TestDevices []TestDevice `json:"test_devices,omitempty"`
 
 	Errors       []Error `json:"errors,omitempty"`
 	ErrorCode    int     `json:"code,omitempty"`
 	ErrorMessage string  `json:"message,omitempty"`
 }
 
+// Report is a result of Reporting API table query (stat/v1/data). Every row
+// contains values of dimensions in order of query dimensions and values of
+// metrics in order of query metrics; Totals, Min and Max are aggregated over
+// all rows, not only returned ones.
+type Report struct {
+	Query     ReportQueryEcho `json:"query"`
+	Data      []ReportRow     `json:"data"`
+	TotalRows int             `json:"total_rows"`
+	Totals    []float64       `json:"totals"`
+	Min       []float64       `json:"min"`
+	Max       []float64       `json:"max"`
+	DataLag   int             `json:"data_lag"`
+	ReportSampling
+}
+
+// ReportSampling describes sample which report is computed on. SampleShare
+// is a share of data in sample; SampleSize and SampleSpace are numbers of
+// rows in sample and in the whole data. Reports which are not sampled have
+// share 1.
+type ReportSampling struct {
+	Sampled     bool    `json:"sampled"`
+	SampleShare float64 `json:"sample_share"`
+	SampleSize  int64   `json:"sample_size"`
+	SampleSpace int64   `json:"sample_space"`
+}
+
+// ReportQueryEcho is a query as it has been understood by Reporting API.
+type ReportQueryEcho struct {
+	IDs        []int          `json:"ids"`
+	Metrics    []string       `json:"metrics"`
+	Dimensions []string       `json:"dimensions"`
+	Sort       []string       `json:"sort"`
+	Date1      string         `json:"date1"`
+	Date2      string         `json:"date2"`
+	Filters    string         `json:"filters"`
+	Limit      int            `json:"limit"`
+	Offset     int            `json:"offset"`
+	Accuracy   ReportAccuracy `json:"accuracy"`
+}
+
+// ByTimeReport is a result of Reporting API time series query
+// (stat/v1/data/bytime). Buckets are beginnings of time intervals in time
+// zone of query; series of every row and totals are aligned with them.
+type ByTimeReport struct {
+	Query         ReportQueryEcho `json:"query"`
+	Data          []ByTimeRow     `json:"data"`
+	TotalRows     int             `json:"total_rows"`
+	Totals        [][]float64     `json:"totals"`
+	TimeIntervals [][]string      `json:"time_intervals"`
+	Buckets       []time.Time     `json:"-"`
+	ReportSampling
+}
+
+// ByTimeRow is a row of time series report. Metrics contains series of every
+// metric in order of query metrics.
+type ByTimeRow struct {
+	Dimensions []ReportDimension `json:"dimensions"`
+	Metrics    [][]float64       `json:"metrics"`
+}
+
+// DrilldownReport is a result of Reporting API tree query
+// (stat/v1/data/drilldown). Rows are values of the next dimension under
+// values of parent dimensions.
+type DrilldownReport struct {
+	Query     ReportQueryEcho `json:"query"`
+	Data      []DrilldownRow  `json:"data"`
+	TotalRows int             `json:"total_rows"`
+	Totals    []float64       `json:"totals"`
+	Min       []float64       `json:"min"`
+	Max       []float64       `json:"max"`
+	ReportSampling
+}
+
+// DrilldownRow is a node of drilldown tree. Expand is true if node has
+// children, i.e. there are dimensions left to drill down.
+type DrilldownRow struct {
+	Dimension ReportDimension `json:"dimension"`
+	Metrics   []float64       `json:"metrics"`
+	Expand    bool            `json:"expand"`
+}
+
+// ComparisonReport is a result of Reporting API segment comparison query
+// (stat/v1/data/comparison).
+type ComparisonReport struct {
+	Query     ReportQueryEcho  `json:"query"`
+	Data      []ComparisonRow  `json:"data"`
+	TotalRows int              `json:"total_rows"`
+	Totals    ComparisonValues `json:"totals"`
+	ReportSampling
+}
+
+// ComparisonRow is a row of comparison report with metrics of both segments.
+type ComparisonRow struct {
+	Dimensions []ReportDimension `json:"dimensions"`
+	Metrics    ComparisonValues  `json:"metrics"`
+}
+
+// ComparisonValues contains values of metrics of segments A and B.
+type ComparisonValues struct {
+	A []float64 `json:"a"`
+	B []float64 `json:"b"`
+}
+
+// ReportRow is a row of Reporting API table.
+type ReportRow struct {
+	Dimensions []ReportDimension `json:"dimensions"`
+	Metrics    []float64         `json:"metrics"`
+}
+
+// ReportDimension is a value of dimension in row of report. ID is an
+// identifier of value (if dimension has one) and Name is its human readable
+// representation.
+type ReportDimension struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// UnmarshalJSON decodes dimension value. Identifiers and names could be
+// strings, numbers or nulls in API responses; all of them are converted to
+// strings.
+func (d *ReportDimension) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ID   json.RawMessage `json:"id"`
+		Name json.RawMessage `json:"name"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	d.ID, d.Name = jsonScalar(raw.ID), jsonScalar(raw.Name)
+	return nil
+}
+
+// jsonScalar converts JSON string, number or boolean to string. Null is
+// converted to empty string.
+func jsonScalar(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+
+	var value string
+
+	if raw[0] == '"' && json.Unmarshal(raw, &value) == nil {
+		return value
+	}
+
+	return string(raw)
+}
+
 type ConnectionType int
 
 const (
@@ -69,3 +386,263 @@ type ImportEvent struct {
 	MNC                int         `json:"mnc,omitempty"`
 	DeviceIPv6         string      `json:"device_ipv6,omitempty"`
 }
+
+// ExportDevice contains identifiers and properties of device, application
+// and network which are common for most of Logs API export resources. It is
+// embedded into row types of resources.
+type ExportDevice struct {
+	DeviceID           uint64 `json:"appmetrica_device_id"`
+	IFA                string `json:"ios_ifa"`
+	IFV                string `json:"ios_ifv"`
+	GoogleAID          string `json:"google_aid"`
+	WindowsAID         string `json:"windows_aid"`
+	OSName             string `json:"os_name"`
+	OSVersion          string `json:"os_version"`
+	DeviceManufacturer string `json:"device_manufacturer"`
+	DeviceModel        string `json:"device_model"`
+	DeviceType         string `json:"device_type"`
+	DeviceLocale       string `json:"device_locale"`
+	AppVersionName     string `json:"app_version_name"`
+	AppPackageName     string `json:"app_package_name"`
+	ConnectionType     string `json:"connection_type"`
+	OperatorName       string `json:"operator_name"`
+	MCC                int    `json:"mcc"`
+	MNC                int    `json:"mnc"`
+	CountryISOCode     string `json:"country_iso_code"`
+	City               string `json:"city"`
+}
+
+// ExportEvent is a row of events export of Logs API.
+type ExportEvent struct {
+	ExportDevice
+	ApplicationID         int             `json:"application_id"`
+	ProfileID             string          `json:"profile_id"`
+	EventName             string          `json:"event_name"`
+	EventJSON             json.RawMessage `json:"event_json"`
+	EventDatetime         time.Time       `json:"event_datetime"`
+	EventTimestamp        int64           `json:"event_timestamp"`
+	EventReceiveDatetime  time.Time       `json:"event_receive_datetime"`
+	EventReceiveTimestamp int64           `json:"event_receive_timestamp"`
+	SessionID             uint64          `json:"session_id"`
+}
+
+// ExportInstallation is a row of installations export of Logs API.
+type ExportInstallation struct {
+	ExportDevice
+	ApplicationID           int       `json:"application_id"`
+	ProfileID               string    `json:"profile_id"`
+	InstallDatetime         time.Time `json:"install_datetime"`
+	InstallTimestamp        int64     `json:"install_timestamp"`
+	InstallReceiveDatetime  time.Time `json:"install_receive_datetime"`
+	InstallReceiveTimestamp int64     `json:"install_receive_timestamp"`
+	InstallIPv6             string    `json:"install_ipv6"`
+	IsReinstallation        bool      `json:"is_reinstallation"`
+	IsReattribution         bool      `json:"is_reattribution"`
+	TrackingID              uint64    `json:"tracking_id"`
+	TrackerName             string    `json:"tracker_name"`
+	PublisherID             uint64    `json:"publisher_id"`
+	PublisherName           string    `json:"publisher_name"`
+	MatchType               string    `json:"match_type"`
+	ClickID                 string    `json:"click_id"`
+	ClickDatetime           time.Time `json:"click_datetime"`
+	ClickTimestamp          int64     `json:"click_timestamp"`
+	ClickIPv6               string    `json:"click_ipv6"`
+	ClickURLParameters      string    `json:"click_url_parameters"`
+}
+
+// ExportClick is a row of clicks export of Logs API.
+type ExportClick struct {
+	ExportDevice
+	ApplicationID      int       `json:"application_id"`
+	ClickID            string    `json:"click_id"`
+	ClickDatetime      time.Time `json:"click_datetime"`
+	ClickTimestamp     int64     `json:"click_timestamp"`
+	ClickIPv6          string    `json:"click_ipv6"`
+	ClickURLParameters string    `json:"click_url_parameters"`
+	ClickUserAgent     string    `json:"click_user_agent"`
+	TrackingID         uint64    `json:"tracking_id"`
+	TrackerName        string    `json:"tracker_name"`
+	PublisherID        uint64    `json:"publisher_id"`
+	PublisherName      string    `json:"publisher_name"`
+}
+
+// ExportPostback is a row of postbacks export of Logs API.
+type ExportPostback struct {
+	ExportDevice
+	ApplicationID         int       `json:"application_id"`
+	AttributedTouchType   string    `json:"attributed_touch_type"`
+	ClickID               string    `json:"click_id"`
+	ClickDatetime         time.Time `json:"click_datetime"`
+	ClickTimestamp        int64     `json:"click_timestamp"`
+	ClickIPv6             string    `json:"click_ipv6"`
+	ClickURLParameters    string    `json:"click_url_parameters"`
+	ClickUserAgent        string    `json:"click_user_agent"`
+	ConversionDatetime    time.Time `json:"conversion_datetime"`
+	ConversionTimestamp   int64     `json:"conversion_timestamp"`
+	EventName             string    `json:"event_name"`
+	InstallDatetime       time.Time `json:"install_datetime"`
+	InstallTimestamp      int64     `json:"install_timestamp"`
+	NotifyingStatus       string    `json:"notifying_status"`
+	PostbackURL           string    `json:"postback_url"`
+	PostbackURLParameters string    `json:"postback_url_parameters"`
+	ResponseBody          string    `json:"response_body"`
+	ResponseCode          int       `json:"response_code"`
+	TrackingID            uint64    `json:"tracking_id"`
+	TrackerName           string    `json:"tracker_name"`
+	PublisherID           uint64    `json:"publisher_id"`
+	PublisherName         string    `json:"publisher_name"`
+}
+
+// ExportSessionStart is a row of sessions_starts export of Logs API.
+type ExportSessionStart struct {
+	ExportDevice
+	ApplicationID                int       `json:"application_id"`
+	ProfileID                    string    `json:"profile_id"`
+	SessionID                    uint64    `json:"session_id"`
+	SessionStartDatetime         time.Time `json:"session_start_datetime"`
+	SessionStartTimestamp        int64     `json:"session_start_timestamp"`
+	SessionStartReceiveDatetime  time.Time `json:"session_start_receive_datetime"`
+	SessionStartReceiveTimestamp int64     `json:"session_start_receive_timestamp"`
+}
+
+// ExportCrash is a row of crashes export of Logs API. Field Crash contains
+// stack trace of crash.
+type ExportCrash struct {
+	ExportDevice
+	ApplicationID         int       `json:"application_id"`
+	ProfileID             string    `json:"profile_id"`
+	Crash                 string    `json:"crash"`
+	CrashID               string    `json:"crash_id"`
+	CrashGroupID          uint64    `json:"crash_group_id"`
+	CrashName             string    `json:"crash_name"`
+	CrashReason           string    `json:"crash_reason"`
+	CrashReasonMessage    string    `json:"crash_reason_message"`
+	CrashBinaryName       string    `json:"crash_binary_name"`
+	CrashFileName         string    `json:"crash_file_name"`
+	CrashMethodName       string    `json:"crash_method_name"`
+	CrashSourceLine       int       `json:"crash_source_line"`
+	CrashDatetime         time.Time `json:"crash_datetime"`
+	CrashTimestamp        int64     `json:"crash_timestamp"`
+	CrashReceiveDatetime  time.Time `json:"crash_receive_datetime"`
+	CrashReceiveTimestamp int64     `json:"crash_receive_timestamp"`
+}
+
+// ExportError is a row of errors export of Logs API. Field Error contains
+// stack trace of error.
+type ExportError struct {
+	ExportDevice
+	ApplicationID         int       `json:"application_id"`
+	ProfileID             string    `json:"profile_id"`
+	Error                 string    `json:"error"`
+	ErrorID               string    `json:"error_id"`
+	ErrorName             string    `json:"error_name"`
+	ErrorDatetime         time.Time `json:"error_datetime"`
+	ErrorTimestamp        int64     `json:"error_timestamp"`
+	ErrorReceiveDatetime  time.Time `json:"error_receive_datetime"`
+	ErrorReceiveTimestamp int64     `json:"error_receive_timestamp"`
+}
+
+// ExportPushToken is a row of push_tokens export of Logs API.
+type ExportPushToken struct {
+	ExportDevice
+	ApplicationID         int       `json:"application_id"`
+	ProfileID             string    `json:"profile_id"`
+	Token                 string    `json:"token"`
+	TokenDatetime         time.Time `json:"token_datetime"`
+	TokenTimestamp        int64     `json:"token_timestamp"`
+	TokenReceiveDatetime  time.Time `json:"token_receive_datetime"`
+	TokenReceiveTimestamp int64     `json:"token_receive_timestamp"`
+}
+
+// ExportProfile is a row of profiles export of Logs API. Only predefined
+// attributes are typed; custom attributes could be read with
+// ExportReader.Map.
+type ExportProfile struct {
+	ApplicationID        int       `json:"application_id"`
+	ProfileID            string    `json:"profile_id"`
+	DeviceID             uint64    `json:"appmetrica_device_id"`
+	Name                 string    `json:"appmetrica_name"`
+	Gender               string    `json:"appmetrica_gender"`
+	BirthDate            time.Time `json:"appmetrica_birth_date"`
+	NotificationsEnabled bool      `json:"appmetrica_notifications_enabled"`
+	Crashes              int       `json:"appmetrica_crashes"`
+	Errors               int       `json:"appmetrica_errors"`
+	Sessions             int       `json:"appmetrica_sessions"`
+	PushOpens            int       `json:"appmetrica_push_opens"`
+	PushSendCount        int       `json:"appmetrica_push_send_count"`
+	FirstSessionDate     time.Time `json:"appmetrica_first_session_date"`
+	LastStartDate        time.Time `json:"appmetrica_last_start_date"`
+	SDKVersion           string    `json:"appmetrica_sdk_version"`
+}
+
+// ExportDeepLink is a row of deeplinks export of Logs API.
+type ExportDeepLink struct {
+	ExportDevice
+	ApplicationID            int       `json:"application_id"`
+	ProfileID                string    `json:"profile_id"`
+	DeepLinkURLScheme        string    `json:"deeplink_url_scheme"`
+	DeepLinkURLHost          string    `json:"deeplink_url_host"`
+	DeepLinkURLPath          string    `json:"deeplink_url_path"`
+	DeepLinkURLParameters    string    `json:"deeplink_url_parameters"`
+	DeepLinkDatetime         time.Time `json:"deeplink_datetime"`
+	DeepLinkTimestamp        int64     `json:"deeplink_timestamp"`
+	DeepLinkReceiveDatetime  time.Time `json:"deeplink_receive_datetime"`
+	DeepLinkReceiveTimestamp int64     `json:"deeplink_receive_timestamp"`
+	IsReengagement           bool      `json:"is_reengagement"`
+	TrackingID               uint64    `json:"tracking_id"`
+	TrackerName              string    `json:"tracker_name"`
+	PublisherID              uint64    `json:"publisher_id"`
+	PublisherName            string    `json:"publisher_name"`
+}
+
+// ExportRevenueEvent is a row of revenue_events export of Logs API.
+type ExportRevenueEvent struct {
+	ExportDevice
+	ApplicationID         int       `json:"application_id"`
+	ProfileID             string    `json:"profile_id"`
+	EventDatetime         time.Time `json:"event_datetime"`
+	EventTimestamp        int64     `json:"event_timestamp"`
+	EventReceiveDatetime  time.Time `json:"event_receive_datetime"`
+	EventReceiveTimestamp int64     `json:"event_receive_timestamp"`
+	RevenuePrice          float64   `json:"revenue_price"`
+	RevenueCurrency       string    `json:"revenue_currency"`
+	RevenueQuantity       int       `json:"revenue_quantity"`
+	RevenueProductID      string    `json:"revenue_product_id"`
+	RevenueOrderID        string    `json:"revenue_order_id"`
+	RevenueOrderIDSource  string    `json:"revenue_order_id_source"`
+	IsRevenueVerified     bool      `json:"is_revenue_verified"`
+}
+
+// ExportEcommerceEvent is a row of ecommerce_events export of Logs API.
+// Content of event (products, order, referrer) is kept as JSON.
+type ExportEcommerceEvent struct {
+	ExportDevice
+	ApplicationID         int             `json:"application_id"`
+	ProfileID             string          `json:"profile_id"`
+	EventDatetime         time.Time       `json:"event_datetime"`
+	EventTimestamp        int64           `json:"event_timestamp"`
+	EventReceiveDatetime  time.Time       `json:"event_receive_datetime"`
+	EventReceiveTimestamp int64           `json:"event_receive_timestamp"`
+	EcomType              string          `json:"ecom_type"`
+	EcomContent           json.RawMessage `json:"ecom_content"`
+}
+
+// ExportAdRevenueEvent is a row of ad_revenue_events export of Logs API.
+type ExportAdRevenueEvent struct {
+	ExportDevice
+	ApplicationID          int             `json:"application_id"`
+	ProfileID              string          `json:"profile_id"`
+	EventDatetime          time.Time       `json:"event_datetime"`
+	EventTimestamp         int64           `json:"event_timestamp"`
+	EventReceiveDatetime   time.Time       `json:"event_receive_datetime"`
+	EventReceiveTimestamp  int64           `json:"event_receive_timestamp"`
+	AdRevenueValue         float64         `json:"ad_revenue_value"`
+	AdRevenueCurrency      string          `json:"ad_revenue_currency"`
+	AdRevenueAdType        string          `json:"ad_revenue_ad_type"`
+	AdRevenueAdNetwork     string          `json:"ad_revenue_ad_network"`
+	AdRevenueAdUnitID      string          `json:"ad_revenue_ad_unit_id"`
+	AdRevenueAdUnitName    string          `json:"ad_revenue_ad_unit_name"`
+	AdRevenueAdPlacementID string          `json:"ad_revenue_ad_placement_id"`
+	AdRevenuePrecision     string          `json:"ad_revenue_precision"`
+	AdRevenuePayload       json.RawMessage `json:"ad_revenue_payload"`
+}